@@ -0,0 +1,103 @@
+// Copyright 2022 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The read-modify-write operations of cache, they run under a single lock
+// acquisition, so the concurrent updates will not be lost.
+// The expired item is treated as not existing.
+
+package lruttl
+
+import "time"
+
+// GetOrAdd returns the existing value of key if it is not expired and loaded is true.
+// Otherwise, it adds the value to cache and returns the value and loaded is false.
+// It returns ErrCacheClosed if the cache is closed, and ErrNotAdded if the value
+// is not stored, e.g. rejected by the admission policy.
+func (c *TypedCache[K, V]) GetOrAdd(key K, value V, ttl ...time.Duration) (actual V, loaded bool, err error) {
+	c.mu.Lock()
+	defer c.unlock()
+	if item := c.validItemLocked(key); item != nil {
+		c.lru.Get(key)
+		return item.value, true, nil
+	}
+	if c.closed {
+		return value, false, ErrCacheClosed
+	}
+	if !c.addLocked(key, c.newItem(value, c.getTTL(ttl...), c.itemCost(key, value))) {
+		return value, false, ErrNotAdded
+	}
+	return value, false, nil
+}
+
+// AddIfAbsent adds the value to cache only if the key does not exist or is expired,
+// it returns true if the value is added.
+func (c *TypedCache[K, V]) AddIfAbsent(key K, value V, ttl ...time.Duration) bool {
+	c.mu.Lock()
+	defer c.unlock()
+	if c.validItemLocked(key) != nil {
+		return false
+	}
+	return c.addLocked(key, c.newItem(value, c.getTTL(ttl...), c.itemCost(key, value)))
+}
+
+// Replace replaces the value of key only if the key exists and is not expired,
+// it returns true if the value is replaced.
+func (c *TypedCache[K, V]) Replace(key K, value V, ttl ...time.Duration) bool {
+	c.mu.Lock()
+	defer c.unlock()
+	if c.validItemLocked(key) == nil {
+		return false
+	}
+	return c.addLocked(key, c.newItem(value, c.getTTL(ttl...), c.itemCost(key, value)))
+}
+
+// CompareAndSwap replaces the value of key only if the key exists, is not expired
+// and its value is equal to old, it returns true if the value is swapped.
+// It panics if the value is not comparable, the same as sync.Map.
+func (c *TypedCache[K, V]) CompareAndSwap(key K, old, new V, ttl ...time.Duration) bool {
+	c.mu.Lock()
+	defer c.unlock()
+	item := c.validItemLocked(key)
+	if item == nil || any(item.value) != any(old) {
+		return false
+	}
+	return c.addLocked(key, c.newItem(new, c.getTTL(ttl...), c.itemCost(key, new)))
+}
+
+// Update sets the value of key by the function, the function receives the old value
+// and whether it exists (not expired), and returns the new value and its ttl
+// (default ttl if the ttl is not greater than 0). It returns the new value.
+// It returns ErrCacheClosed if the cache is closed, and ErrNotAdded if the value
+// is not stored, e.g. rejected by the admission policy.
+// The function is called with lock, so it should not call the functions of cache.
+func (c *TypedCache[K, V]) Update(key K, fn func(old V, exists bool) (V, time.Duration)) (V, error) {
+	c.mu.Lock()
+	defer c.unlock()
+	var old V
+	if c.closed {
+		return old, ErrCacheClosed
+	}
+	item := c.validItemLocked(key)
+	if item != nil {
+		old = item.value
+	}
+	value, ttl := fn(old, item != nil)
+	if ttl <= 0 {
+		ttl = c.ttl
+	}
+	if !c.addLocked(key, c.newItem(value, ttl, c.itemCost(key, value))) {
+		return value, ErrNotAdded
+	}
+	return value, nil
+}
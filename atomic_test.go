@@ -0,0 +1,153 @@
+package lruttl
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetOrAdd(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := NewTyped[string, int](10, time.Minute)
+	value, loaded, err := cache.GetOrAdd("a", 1)
+	assert.Nil(err)
+	assert.False(loaded)
+	assert.Equal(1, value)
+
+	value, loaded, err = cache.GetOrAdd("a", 2)
+	assert.Nil(err)
+	assert.True(loaded)
+	assert.Equal(1, value)
+
+	// 过期的数据则重新添加
+	cache.Add("b", 1, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	value, loaded, err = cache.GetOrAdd("b", 2, time.Second)
+	assert.Nil(err)
+	assert.False(loaded)
+	assert.Equal(2, value)
+	assert.True(cache.TTL("b") <= time.Second)
+}
+
+func TestGetOrAddNotAdded(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := NewTyped[string, int](2, time.Minute, CacheTinyLFUOption(0))
+	for _, key := range []string{"a", "b"} {
+		cache.Add(key, 1)
+		for i := 0; i < 3; i++ {
+			cache.Get(key)
+		}
+	}
+	// 准入策略拒绝
+	_, loaded, err := cache.GetOrAdd("c", 1)
+	assert.Equal(ErrNotAdded, err)
+	assert.False(loaded)
+	_, err = cache.Update("c", func(old int, exists bool) (int, time.Duration) {
+		return 1, 0
+	})
+	assert.Equal(ErrNotAdded, err)
+	_, ok := cache.Peek("c")
+	assert.False(ok)
+
+	// 关闭后已存在的数据仍可读取
+	cache.Close()
+	value, loaded, err := cache.GetOrAdd("a", 2)
+	assert.Nil(err)
+	assert.True(loaded)
+	assert.Equal(1, value)
+	_, _, err = cache.GetOrAdd("d", 1)
+	assert.Equal(ErrCacheClosed, err)
+	_, err = cache.Update("a", func(old int, exists bool) (int, time.Duration) {
+		return old + 1, 0
+	})
+	assert.Equal(ErrCacheClosed, err)
+}
+
+func TestAddIfAbsent(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := NewTyped[string, int](10, time.Minute)
+	assert.True(cache.AddIfAbsent("a", 1))
+	assert.False(cache.AddIfAbsent("a", 2))
+	value, _ := cache.Get("a")
+	assert.Equal(1, value)
+
+	cache.Add("b", 1, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	assert.True(cache.AddIfAbsent("b", 2))
+}
+
+func TestReplace(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := NewTyped[string, int](10, time.Minute)
+	assert.False(cache.Replace("a", 1))
+	_, ok := cache.Peek("a")
+	assert.False(ok)
+
+	cache.Add("a", 1)
+	assert.True(cache.Replace("a", 2))
+	value, _ := cache.Get("a")
+	assert.Equal(2, value)
+
+	cache.Add("b", 1, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	assert.False(cache.Replace("b", 2))
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := New(10, time.Minute)
+	assert.False(cache.CompareAndSwap("a", 1, 2))
+	cache.Add("a", 1)
+	assert.False(cache.CompareAndSwap("a", 2, 3))
+	assert.True(cache.CompareAndSwap("a", 1, 3))
+	value, _ := cache.Get("a")
+	assert.Equal(3, value)
+
+	// 不可比较的类型
+	cache.Add("b", []int{1})
+	assert.Panics(func() {
+		cache.CompareAndSwap("b", []int{1}, []int{2})
+	})
+}
+
+func TestUpdate(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := NewTyped[string, int](10, time.Minute)
+	incr := func(old int, exists bool) (int, time.Duration) {
+		if !exists {
+			return 1, 0
+		}
+		return old + 1, 0
+	}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cache.Update("a", incr)
+			assert.Nil(err)
+		}()
+	}
+	wg.Wait()
+	value, ok := cache.Get("a")
+	assert.True(ok)
+	assert.Equal(100, value)
+	ttl := cache.TTL("a")
+	assert.True(ttl > 59*time.Second)
+
+	value, err := cache.Update("b", func(old int, exists bool) (int, time.Duration) {
+		assert.False(exists)
+		return 2, time.Second
+	})
+	assert.Nil(err)
+	assert.Equal(2, value)
+	assert.True(cache.TTL("b") <= time.Second)
+}
@@ -0,0 +1,79 @@
+// Copyright 2022 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The bulk operations of cache, each of them acquires the lock only once.
+
+package lruttl
+
+import "time"
+
+// GetMany returns the values of keys which exist and are not expired,
+// it is the same as calling Get for each key.
+func (c *TypedCache[K, V]) GetMany(keys []K) map[K]V {
+	c.mu.Lock()
+	defer c.unlock()
+	result := make(map[K]V, len(keys))
+	for _, key := range keys {
+		value, ok, _ := c.getLocked(key)
+		if ok {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+// AddMany adds the values to cache, it will use default ttl if the ttl is nil.
+func (c *TypedCache[K, V]) AddMany(values map[K]V, ttl ...time.Duration) {
+	c.mu.Lock()
+	defer c.unlock()
+	d := c.getTTL(ttl...)
+	for key, value := range values {
+		c.addLocked(key, c.newItem(value, d, c.itemCost(key, value)))
+	}
+}
+
+// RemoveMany removes the keys from cache, it returns the count of removed keys.
+func (c *TypedCache[K, V]) RemoveMany(keys []K) int {
+	c.mu.Lock()
+	defer c.unlock()
+	count := 0
+	for _, key := range keys {
+		if c.removeLocked(key, EvictReasonRemoved) {
+			count++
+		}
+	}
+	return count
+}
+
+// RemoveFunc removes the items which the function returns true, include the expired items.
+// It returns the count of removed items.
+// The function is called with lock, so it should not call the functions of cache.
+func (c *TypedCache[K, V]) RemoveFunc(fn func(key K, value V) bool) int {
+	c.mu.Lock()
+	defer c.unlock()
+	count := 0
+	for _, key := range c.lru.Keys() {
+		data, _ := c.lru.Peek(key)
+		item, _ := data.(*cacheItem[V])
+		if item == nil {
+			continue
+		}
+		k, _ := key.(K)
+		if fn(k, item.value) {
+			c.removeLocked(key, EvictReasonRemoved)
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,71 @@
+package lruttl
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAddMany(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := NewTyped[string, int](10, time.Minute)
+	cache.AddMany(map[string]int{
+		"a": 1,
+		"b": 2,
+	})
+	cache.AddMany(map[string]int{
+		"c": 3,
+	}, time.Nanosecond)
+	assert.Equal(3, cache.Len())
+	assert.True(cache.TTL("a") > 59*time.Second)
+	time.Sleep(time.Millisecond)
+
+	result := cache.GetMany([]string{"a", "b", "c", "d"})
+	assert.Equal(map[string]int{
+		"a": 1,
+		"b": 2,
+	}, result)
+	// 过期的数据被删除
+	assert.Equal(2, cache.Len())
+	stats := cache.Stats()
+	assert.Equal(uint64(2), stats.Hits)
+	assert.Equal(uint64(1), stats.Misses)
+	assert.Equal(uint64(1), stats.ExpiredHits)
+}
+
+func TestRemoveMany(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := NewTyped[string, int](10, time.Minute)
+	cache.AddMany(map[string]int{
+		"a": 1,
+		"b": 2,
+		"c": 3,
+	})
+	assert.Equal(2, cache.RemoveMany([]string{"a", "b", "d"}))
+	assert.Equal([]string{"c"}, cache.Keys())
+	assert.Equal(uint64(2), cache.Stats().Removals)
+}
+
+func TestRemoveFunc(t *testing.T) {
+	assert := assert.New(t)
+
+	reasons := make([]EvictReason, 0)
+	cache := NewTyped[string, int](10, time.Minute, TypedCacheEvictedReasonOption(func(key string, value int, reason EvictReason) {
+		reasons = append(reasons, reason)
+	}))
+	cache.AddMany(map[string]int{
+		"user:1":  1,
+		"user:2":  2,
+		"order:1": 3,
+	})
+	count := cache.RemoveFunc(func(key string, value int) bool {
+		return strings.HasPrefix(key, "user:")
+	})
+	assert.Equal(2, count)
+	assert.Equal([]string{"order:1"}, cache.Keys())
+	assert.Equal([]EvictReason{EvictReasonRemoved, EvictReasonRemoved}, reasons)
+}
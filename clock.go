@@ -0,0 +1,151 @@
+// Copyright 2022 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lruttl
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Clock is the clock of cache expiration
+type Clock interface {
+	// Now returns the current time in nanoseconds, it should be the unix time
+	// (except the monotonic clock) to convert time.Time to ttl correctly.
+	Now() int64
+}
+
+// clockUntil returns the duration until t by the clock
+func clockUntil(clock Clock, t time.Time) time.Duration {
+	// 单调时钟不是unix时间，使用系统时间计算
+	if _, ok := clock.(monotonicClock); ok {
+		return time.Until(t)
+	}
+	return time.Duration(t.UnixNano() - clock.Now())
+}
+
+type wallClock struct{}
+
+// NewWallClock returns a clock based on time.Now, it is the default clock of cache
+func NewWallClock() Clock {
+	return wallClock{}
+}
+
+func (wallClock) Now() int64 {
+	return time.Now().UnixNano()
+}
+
+type monotonicClock struct{}
+
+// NewMonotonicClock returns a monotonic clock based on NanoTime,
+// it is faster than wall clock and not affected by the change of system time.
+func NewMonotonicClock() Clock {
+	return monotonicClock{}
+}
+
+func (monotonicClock) Now() int64 {
+	return NanoTime()
+}
+
+// FakeClock is a clock which is advanced manually, it is useful for test
+type FakeClock struct {
+	now atomic.Int64
+}
+
+// NewFakeClock returns a fake clock of the time
+func NewFakeClock(t time.Time) *FakeClock {
+	c := &FakeClock{}
+	c.Set(t)
+	return c
+}
+
+// Now returns the current time of fake clock
+func (c *FakeClock) Now() int64 {
+	return c.now.Load()
+}
+
+// Advance moves the fake clock forward by the duration
+func (c *FakeClock) Advance(d time.Duration) {
+	c.now.Add(d.Nanoseconds())
+}
+
+// Set sets the time of fake clock
+func (c *FakeClock) Set(t time.Time) {
+	c.now.Store(t.UnixNano())
+}
+
+// CacheClockOption sets the clock of cache expiration, it is wall clock by default
+func CacheClockOption(clock Clock) CacheOption {
+	return func(opts *cacheOptions) {
+		opts.clock = clock
+	}
+}
+
+// L2CacheClockOption sets the clock of lru cache expiration for l2cache
+func L2CacheClockOption(clock Clock) L2CacheOption {
+	return func(c *L2Cache) {
+		c.clock = clock
+	}
+}
+
+// CoarseClock is a clock which caches the time and updates it by ticker.
+// It is cheaper than wall clock for the hot path of Get, but the precision is
+// the resolution: the items may be expired up to one resolution late,
+// and the ttl smaller than resolution is not accurate.
+type CoarseClock struct {
+	now      atomic.Int64
+	ticker   *time.Ticker
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewCoarseClock returns a coarse clock of the resolution, it should be stopped if no longer used.
+// The resolution will be one millisecond if it is not gt 0.
+func NewCoarseClock(resolution time.Duration) *CoarseClock {
+	if resolution <= 0 {
+		resolution = time.Millisecond
+	}
+	c := &CoarseClock{
+		ticker: time.NewTicker(resolution),
+		done:   make(chan struct{}),
+	}
+	c.now.Store(time.Now().UnixNano())
+	go c.run()
+	return c
+}
+
+func (c *CoarseClock) run() {
+	for {
+		select {
+		case <-c.done:
+			return
+		case t := <-c.ticker.C:
+			c.now.Store(t.UnixNano())
+		}
+	}
+}
+
+// Now returns the cached time of coarse clock
+func (c *CoarseClock) Now() int64 {
+	return c.now.Load()
+}
+
+// Stop stops updating the time of coarse clock
+func (c *CoarseClock) Stop() {
+	c.stopOnce.Do(func() {
+		c.ticker.Stop()
+		close(c.done)
+	})
+}
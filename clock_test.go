@@ -0,0 +1,209 @@
+package lruttl
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClock(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Now().UnixNano()
+	assert.True(NewWallClock().Now() >= now)
+
+	monotonic := NewMonotonicClock()
+	start := monotonic.Now()
+	time.Sleep(time.Millisecond)
+	assert.True(monotonic.Now()-start >= time.Millisecond.Nanoseconds())
+
+	tm := time.Unix(1000, 0)
+	fake := NewFakeClock(tm)
+	assert.Equal(tm.UnixNano(), fake.Now())
+	fake.Advance(time.Second)
+	assert.Equal(tm.Add(time.Second).UnixNano(), fake.Now())
+	fake.Set(tm)
+	assert.Equal(tm.UnixNano(), fake.Now())
+}
+
+func TestCacheClock(t *testing.T) {
+	assert := assert.New(t)
+
+	clock := NewFakeClock(time.Now())
+	cache := New(10, time.Minute, CacheClockOption(clock))
+	cache.Add("a", 1)
+	cache.Add("b", 2, time.Second)
+	assert.Equal(time.Second, cache.TTL("b"))
+
+	clock.Advance(500 * time.Millisecond)
+	assert.Equal(500*time.Millisecond, cache.TTL("b"))
+	value, ok := cache.Get("b")
+	assert.True(ok)
+	assert.Equal(2, value)
+
+	clock.Advance(time.Second)
+	_, ok = cache.Get("b")
+	assert.False(ok)
+	_, ok = cache.Peek("a")
+	assert.True(ok)
+
+	// 修改过期时间
+	assert.True(cache.Expire("a", time.Second))
+	clock.Advance(2 * time.Second)
+	_, ok = cache.Get("a")
+	assert.False(ok)
+}
+
+func TestCacheClockExpireAt(t *testing.T) {
+	assert := assert.New(t)
+
+	tm := time.Unix(1000, 0)
+	clock := NewFakeClock(tm)
+	cache := New(10, time.Minute, CacheClockOption(clock))
+	cache.Add("a", 1)
+	// 使用缓存的时钟计算ttl
+	assert.True(cache.ExpireAt("a", tm.Add(10*time.Second)))
+	assert.Equal(10*time.Second, cache.TTL("a"))
+	clock.Advance(11 * time.Second)
+	_, ok := cache.Get("a")
+	assert.False(ok)
+
+	// 时间不在当前时间之后则删除
+	cache.Add("b", 1)
+	assert.True(cache.ExpireAt("b", tm))
+	assert.Equal(time.Duration(-2), cache.TTL("b"))
+
+	// 单调时钟使用系统时间计算
+	monotonicCache := New(10, time.Minute, CacheClockOption(NewMonotonicClock()))
+	monotonicCache.Add("a", 1)
+	assert.True(monotonicCache.ExpireAt("a", time.Now().Add(10*time.Second)))
+	ttl := monotonicCache.TTL("a")
+	assert.True(ttl > 9*time.Second && ttl <= 10*time.Second)
+}
+
+func TestCacheClockSnapshot(t *testing.T) {
+	assert := assert.New(t)
+
+	// 时钟与系统时间不一致，快照中保存的仍为系统时间
+	clock := NewFakeClock(time.Unix(0, 0))
+	cache := New(10, time.Minute, CacheClockOption(clock))
+	cache.Add("a", "1")
+	cache.Add("b", "2", 0)
+	assert.True(cache.Persist("b"))
+
+	buf := bytes.Buffer{}
+	assert.Nil(cache.Save(&buf))
+
+	other := New(10, time.Minute)
+	assert.Nil(other.Load(bytes.NewReader(buf.Bytes())))
+	ttl := other.TTL("a")
+	assert.True(ttl > 59*time.Second && ttl <= time.Minute)
+	assert.Equal(time.Duration(-1), other.TTL("b"))
+
+	clock = NewFakeClock(time.Unix(100, 0))
+	fakeCache := New(10, time.Minute, CacheClockOption(clock))
+	assert.Nil(fakeCache.Load(bytes.NewReader(buf.Bytes())))
+	ttl = fakeCache.TTL("a")
+	assert.True(ttl > 59*time.Second && ttl <= time.Minute)
+	clock.Advance(time.Minute)
+	_, ok := fakeCache.Get("a")
+	assert.False(ok)
+	_, ok = fakeCache.Get("b")
+	assert.True(ok)
+}
+
+func TestRingCacheClock(t *testing.T) {
+	assert := assert.New(t)
+
+	clock := NewFakeClock(time.Now())
+	cache := NewRing(RingCacheParams{
+		Size:       2,
+		MaxEntries: 10,
+		DefaultTTL: time.Minute,
+	}, CacheClockOption(clock))
+	cache.Get("a").Add("a", 1)
+	clock.Advance(2 * time.Minute)
+	_, ok := cache.Get("a").Get("a")
+	assert.False(ok)
+}
+
+func TestL2CacheClock(t *testing.T) {
+	assert := assert.New(t)
+
+	clock := NewFakeClock(time.Now())
+	l2 := NewL2Cache(&testSlowCache{
+		data: make(map[string][]byte),
+	}, 10, time.Minute, L2CacheClockOption(clock))
+	assert.Nil(l2.SetBytes(context.Background(), "a", []byte("1")))
+	assert.Equal(time.Minute, l2.ttlCache.TTL("a"))
+	clock.Advance(2 * time.Minute)
+	_, ok := l2.ttlCache.Peek("a")
+	assert.False(ok)
+}
+
+func TestCoarseClock(t *testing.T) {
+	assert := assert.New(t)
+
+	clock := NewCoarseClock(time.Millisecond)
+	defer clock.Stop()
+	start := clock.Now()
+	assert.True(start > 0)
+	time.Sleep(20 * time.Millisecond)
+	assert.True(clock.Now() > start)
+
+	cache := New(10, time.Minute, CacheClockOption(clock))
+	cache.Add("a", 1, 5*time.Millisecond)
+	_, ok := cache.Get("a")
+	assert.True(ok)
+	time.Sleep(20 * time.Millisecond)
+	_, ok = cache.Get("a")
+	assert.False(ok)
+
+	// 停止后时间不再更新
+	clock.Stop()
+	clock.Stop()
+	time.Sleep(5 * time.Millisecond)
+	now := clock.Now()
+	time.Sleep(5 * time.Millisecond)
+	assert.Equal(now, clock.Now())
+}
+
+func BenchmarkCacheGetWallClock(b *testing.B) {
+	cache := New(10, time.Minute)
+	cache.Add("a", 1)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cache.Get("a")
+	}
+}
+
+func BenchmarkCacheGetCoarseClock(b *testing.B) {
+	clock := NewCoarseClock(time.Millisecond)
+	defer clock.Stop()
+	cache := New(10, time.Minute, CacheClockOption(clock))
+	cache.Add("a", 1)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cache.Get("a")
+	}
+}
+
+func BenchmarkWallClock(b *testing.B) {
+	clock := NewWallClock()
+	for i := 0; i < b.N; i++ {
+		clock.Now()
+	}
+}
+
+func BenchmarkCoarseClock(b *testing.B) {
+	clock := NewCoarseClock(time.Millisecond)
+	defer clock.Stop()
+	for i := 0; i < b.N; i++ {
+		clock.Now()
+	}
+}
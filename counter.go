@@ -0,0 +1,92 @@
+// Copyright 2022 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lruttl
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// ErrCacheClosed is the error of writing to a closed cache
+var ErrCacheClosed = errors.New("cache is closed")
+
+// ErrOverflow is the error of counter overflow
+var ErrOverflow = errors.New("increment or decrement would overflow")
+
+// ErrNotAdded is the error of value not being added to cache,
+// it is rejected by the admission policy or evicted by the max cost
+var ErrNotAdded = errors.New("value is not added to cache")
+
+// IncrBy increases the int64 counter of key by delta and returns the new value.
+// The counter is created with the ttl (default ttl if not set) if it does not exist or is expired,
+// otherwise its expired time is kept. It returns ErrInvalidType if the value is not int64,
+// and ErrNotAdded if the counter can not be stored.
+func (c *TypedCache[K, V]) IncrBy(key K, delta int64, ttl ...time.Duration) (int64, error) {
+	return c.incrBy(key, delta, false, ttl...)
+}
+
+// DecrBy decreases the int64 counter of key by delta and returns the new value,
+// it is the same as IncrBy with negative delta.
+// It returns ErrOverflow if delta is math.MinInt64, which can not be negated.
+func (c *TypedCache[K, V]) DecrBy(key K, delta int64, ttl ...time.Duration) (int64, error) {
+	// math.MinInt64取反会溢出
+	if delta == math.MinInt64 {
+		return 0, ErrOverflow
+	}
+	return c.incrBy(key, -delta, false, ttl...)
+}
+
+// IncrByResetTTL is the same as IncrBy, but the expired time of counter
+// is reset by the ttl (default ttl if not set) on each increment.
+func (c *TypedCache[K, V]) IncrByResetTTL(key K, delta int64, ttl ...time.Duration) (int64, error) {
+	return c.incrBy(key, delta, true, ttl...)
+}
+
+func (c *TypedCache[K, V]) incrBy(key K, delta int64, resetTTL bool, ttl ...time.Duration) (int64, error) {
+	c.mu.Lock()
+	defer c.unlock()
+	if c.closed {
+		return 0, ErrCacheClosed
+	}
+	item := c.validItemLocked(key)
+	var current int64
+	if item != nil {
+		v, ok := any(item.value).(int64)
+		if !ok {
+			return 0, ErrInvalidType
+		}
+		current = v
+	}
+	count := current + delta
+	if (delta > 0 && count < current) || (delta < 0 && count > current) {
+		return 0, ErrOverflow
+	}
+	value, ok := any(count).(V)
+	if !ok {
+		return 0, ErrInvalidType
+	}
+	// 已存在且不重置ttl，则只更新数据
+	if item != nil && !resetTTL {
+		item.value = value
+		c.lru.Get(key)
+		return count, nil
+	}
+	// 未能添加（如准入策略拒绝）时返回出错，避免计数丢失
+	if !c.addLocked(key, c.newItem(value, c.getTTL(ttl...), c.itemCost(key, value))) {
+		return 0, ErrNotAdded
+	}
+	return count, nil
+}
@@ -0,0 +1,108 @@
+package lruttl
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIncrBy(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := New(10, time.Minute)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cache.IncrBy("a", 2, time.Second)
+			assert.Nil(err)
+		}()
+	}
+	wg.Wait()
+	value, ok := cache.Get("a")
+	assert.True(ok)
+	assert.Equal(int64(200), value)
+
+	count, err := cache.DecrBy("a", 50)
+	assert.Nil(err)
+	assert.Equal(int64(150), count)
+
+	// 创建时使用ttl，更新时保留原有的过期时间
+	ttl := cache.TTL("a")
+	assert.True(ttl > 0 && ttl <= time.Second)
+	_, err = cache.IncrBy("a", 1, time.Hour)
+	assert.Nil(err)
+	assert.True(cache.TTL("a") <= time.Second)
+
+	// 重置过期时间
+	count, err = cache.IncrByResetTTL("a", 1, time.Hour)
+	assert.Nil(err)
+	assert.Equal(int64(152), count)
+	assert.True(cache.TTL("a") > time.Second)
+
+	// 过期后重新创建
+	cache.Add("b", int64(10), time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	count, err = cache.DecrBy("b", 1)
+	assert.Nil(err)
+	assert.Equal(int64(-1), count)
+	assert.True(cache.TTL("b") > 59*time.Second)
+}
+
+func TestIncrByError(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := New(10, time.Minute)
+	cache.Add("a", 1)
+	_, err := cache.IncrBy("a", 1)
+	assert.Equal(ErrInvalidType, err)
+
+	cache.Add("b", int64(math.MaxInt64))
+	_, err = cache.IncrBy("b", 1)
+	assert.Equal(ErrOverflow, err)
+	cache.Add("c", int64(math.MinInt64))
+	_, err = cache.DecrBy("c", 1)
+	assert.Equal(ErrOverflow, err)
+	// math.MinInt64无法取反
+	_, err = cache.DecrBy("d", math.MinInt64)
+	assert.Equal(ErrOverflow, err)
+	_, ok := cache.Peek("d")
+	assert.False(ok)
+
+	// 类型不匹配的缓存
+	typedCache := NewTyped[string, string](10, time.Minute)
+	_, err = typedCache.IncrBy("a", 1)
+	assert.Equal(ErrInvalidType, err)
+
+	int64Cache := NewTyped[string, int64](10, time.Minute)
+	count, err := int64Cache.IncrBy("a", 1)
+	assert.Nil(err)
+	assert.Equal(int64(1), count)
+}
+
+func TestIncrByNotAdded(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := New(2, time.Minute, CacheTinyLFUOption(0))
+	for _, key := range []string{"a", "b"} {
+		cache.Add(key, 1)
+		for i := 0; i < 3; i++ {
+			cache.Get(key)
+		}
+	}
+	// 准入策略拒绝新的计数器
+	for i := 0; i < 3; i++ {
+		_, err := cache.IncrBy("counter", 1)
+		assert.Equal(ErrNotAdded, err)
+	}
+	_, ok := cache.Peek("counter")
+	assert.False(ok)
+
+	cache.Close()
+	_, err := cache.IncrBy("a", 1)
+	assert.Equal(ErrCacheClosed, err)
+}
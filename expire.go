@@ -0,0 +1,100 @@
+// Copyright 2022 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lruttl
+
+import (
+	"math"
+	"time"
+)
+
+// noExpiration is the expired time of persisted item
+const noExpiration = math.MaxInt64
+
+// validItemLocked returns the item which is not expired, it should be called with lock
+func (c *TypedCache[K, V]) validItemLocked(key K) *cacheItem[V] {
+	data, ok := c.lru.Peek(key)
+	if !ok {
+		return nil
+	}
+	item, _ := data.(*cacheItem[V])
+	if item == nil || item.isExpired(c.clock.Now()) {
+		return nil
+	}
+	return item
+}
+
+// writableItemLocked returns the item which is not expired if the cache is not closed,
+// it should be called with lock
+func (c *TypedCache[K, V]) writableItemLocked(key K) *cacheItem[V] {
+	if c.closed {
+		return nil
+	}
+	return c.validItemLocked(key)
+}
+
+// Expire sets the ttl of key, it returns false if the key does not exist or is expired.
+// The key will be removed if the ttl is not greater than 0.
+func (c *TypedCache[K, V]) Expire(key K, ttl time.Duration) bool {
+	c.mu.Lock()
+	defer c.unlock()
+	item := c.writableItemLocked(key)
+	if item == nil {
+		return false
+	}
+	// 与redis一致，ttl不大于0则删除
+	if ttl <= 0 {
+		c.removeLocked(key, EvictReasonExpired)
+		return true
+	}
+	item.ttl = ttl.Nanoseconds()
+	item.expiredAt = c.clock.Now() + item.ttl
+	return true
+}
+
+// ExpireAt sets the expired time of key, it returns false if the key does not exist or is expired.
+// The key will be removed if the time is not after now of the cache's clock.
+func (c *TypedCache[K, V]) ExpireAt(key K, t time.Time) bool {
+	return c.Expire(key, clockUntil(c.clock, t))
+}
+
+// Touch marks the key as recently used and resets its expired time by the original ttl,
+// it returns false if the key does not exist or is expired.
+func (c *TypedCache[K, V]) Touch(key K) bool {
+	c.mu.Lock()
+	defer c.unlock()
+	item := c.writableItemLocked(key)
+	if item == nil {
+		return false
+	}
+	c.lru.Get(key)
+	item.slide(c.clock.Now())
+	return true
+}
+
+// Persist removes the expiration of key, so it will never expire,
+// it returns false if the key does not exist or is expired.
+// The TTL of persisted key is -1, the same as redis.
+func (c *TypedCache[K, V]) Persist(key K) bool {
+	c.mu.Lock()
+	defer c.unlock()
+	item := c.writableItemLocked(key)
+	if item == nil {
+		return false
+	}
+	item.expiredAt = noExpiration
+	item.ttl = 0
+	item.deadline = 0
+	return true
+}
@@ -0,0 +1,79 @@
+package lruttl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpire(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := New(10, time.Minute)
+	assert.False(cache.Expire("a", time.Second))
+
+	cache.Add("a", 1)
+	assert.True(cache.Expire("a", time.Second))
+	ttl := cache.TTL("a")
+	assert.True(ttl > 900*time.Millisecond && ttl <= time.Second)
+
+	assert.True(cache.ExpireAt("a", time.Now().Add(2*time.Second)))
+	ttl = cache.TTL("a")
+	assert.True(ttl > time.Second && ttl <= 2*time.Second)
+
+	// ttl小于等于0则删除
+	assert.True(cache.Expire("a", 0))
+	assert.Equal(time.Duration(-2), cache.TTL("a"))
+
+	// 已过期的数据
+	cache.Add("b", 1, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	assert.False(cache.Expire("b", time.Second))
+	assert.False(cache.Touch("b"))
+	assert.False(cache.Persist("b"))
+}
+
+func TestTouch(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := New(2, time.Minute)
+	assert.False(cache.Touch("a"))
+
+	cache.Add("a", 1, time.Second)
+	cache.Add("b", 1)
+	time.Sleep(10 * time.Millisecond)
+	assert.True(cache.Touch("a"))
+	// 重置过期时间并且设置为最近使用
+	ttl := cache.TTL("a")
+	assert.True(ttl > 990*time.Millisecond)
+	assert.Equal([]Key{"b", "a"}, cache.Keys())
+
+	// 使用新的ttl重置
+	assert.True(cache.Expire("a", 2*time.Second))
+	time.Sleep(10 * time.Millisecond)
+	assert.True(cache.Touch("a"))
+	ttl = cache.TTL("a")
+	assert.True(ttl > 1990*time.Millisecond)
+}
+
+func TestPersist(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := New(10, time.Minute, CacheSlidingOption(time.Second))
+	assert.False(cache.Persist("a"))
+
+	cache.Add("a", 1, 10*time.Millisecond)
+	assert.True(cache.Persist("a"))
+	assert.Equal(time.Duration(-1), cache.TTL("a"))
+	time.Sleep(20 * time.Millisecond)
+	value, ok := cache.Get("a")
+	assert.True(ok)
+	assert.Equal(1, value)
+	// 永不过期的数据touch后也不会过期
+	assert.True(cache.Touch("a"))
+	assert.Equal(time.Duration(-1), cache.TTL("a"))
+
+	assert.True(cache.Expire("a", time.Second))
+	assert.True(cache.TTL("a") > 0)
+}
@@ -0,0 +1,146 @@
+// Copyright 2022 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lruttl
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// InvalidationMessage is the message of keys which should be removed from lru cache
+type InvalidationMessage struct {
+	// Origin is the id of l2cache which publishes the message
+	Origin string `json:"origin"`
+	// Keys are the keys with prefix
+	Keys []string `json:"keys"`
+}
+
+// Invalidator publishes and subscribes the invalidation messages between the instances of l2cache,
+// it is usually implemented by pub/sub of redis.
+type Invalidator interface {
+	// Publish publishes the message to all subscribers
+	Publish(ctx context.Context, msg InvalidationMessage) error
+	// Subscribe registers the handler of messages, it returns the function to unsubscribe
+	Subscribe(handler func(msg InvalidationMessage)) func()
+}
+
+var invalidationSeq atomic.Uint64
+
+// newInvalidationOrigin returns a unique id of l2cache
+func newInvalidationOrigin() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 36) + "-" +
+		strconv.FormatUint(uint64(FastRand()), 36) + "-" +
+		strconv.FormatUint(invalidationSeq.Add(1), 36)
+}
+
+// L2CacheInvalidatorOption sets the invalidator for l2cache, the keys of Set, SetBytes and Del
+// will be published and removed from the lru cache of the other instances.
+// The write does not fail if publishing fails, because both caches have been written,
+// use L2CachePublishErrorOption to handle the error.
+// The l2cache should be closed to unsubscribe if it is no longer used.
+func L2CacheInvalidatorOption(invalidator Invalidator) L2CacheOption {
+	return func(c *L2Cache) {
+		c.invalidator = invalidator
+	}
+}
+
+// L2CachePublishErrorOption sets the function which is called when publishing
+// the invalidation fails, the other instances may serve the old data until it is expired.
+func L2CachePublishErrorOption(fn func(msg InvalidationMessage, err error)) L2CacheOption {
+	return func(c *L2Cache) {
+		c.onPublishError = fn
+	}
+}
+
+// publish publishes the invalidation of keys, it does nothing if invalidator is not set.
+// The error is passed to the publish error function instead of returning.
+func (l2 *L2Cache) publish(ctx context.Context, keys ...string) {
+	if l2.invalidator == nil || len(keys) == 0 {
+		return
+	}
+	msg := InvalidationMessage{
+		Origin: l2.id,
+		Keys:   keys,
+	}
+	err := l2.invalidator.Publish(ctx, msg)
+	if err != nil && l2.onPublishError != nil {
+		l2.onPublishError(msg, err)
+	}
+}
+
+// invalidate removes the keys of message from lru cache
+func (l2 *L2Cache) invalidate(msg InvalidationMessage) {
+	// 忽略自身发布的消息
+	if msg.Origin == l2.id {
+		return
+	}
+	for _, key := range msg.Keys {
+		l2.ttlCache.Remove(key)
+	}
+}
+
+// Close unsubscribes the invalidation messages
+func (l2 *L2Cache) Close() {
+	if l2.unsubscribe != nil {
+		l2.unsubscribe()
+	}
+}
+
+// MemoryInvalidator is an in-process invalidator, the messages are delivered synchronously.
+// It is useful for test or the instances in the same process.
+type MemoryInvalidator struct {
+	mu       sync.RWMutex
+	seq      uint64
+	handlers map[uint64]func(msg InvalidationMessage)
+}
+
+// NewMemoryInvalidator returns a new in-process invalidator
+func NewMemoryInvalidator() *MemoryInvalidator {
+	return &MemoryInvalidator{
+		handlers: make(map[uint64]func(msg InvalidationMessage)),
+	}
+}
+
+// Publish delivers the message to all subscribers
+func (m *MemoryInvalidator) Publish(_ context.Context, msg InvalidationMessage) error {
+	m.mu.RLock()
+	handlers := make([]func(msg InvalidationMessage), 0, len(m.handlers))
+	for _, handler := range m.handlers {
+		handlers = append(handlers, handler)
+	}
+	m.mu.RUnlock()
+	// 在锁外执行，避免处理函数中再次订阅或取消订阅导致死锁
+	for _, handler := range handlers {
+		handler(msg)
+	}
+	return nil
+}
+
+// Subscribe registers the handler of messages
+func (m *MemoryInvalidator) Subscribe(handler func(msg InvalidationMessage)) func() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.seq++
+	id := m.seq
+	m.handlers[id] = handler
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.handlers, id)
+	}
+}
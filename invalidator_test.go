@@ -0,0 +1,143 @@
+package lruttl
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryInvalidator(t *testing.T) {
+	assert := assert.New(t)
+
+	invalidator := NewMemoryInvalidator()
+	messages := make([]InvalidationMessage, 0)
+	unsubscribe := invalidator.Subscribe(func(msg InvalidationMessage) {
+		messages = append(messages, msg)
+	})
+	msg := InvalidationMessage{
+		Origin: "a",
+		Keys:   []string{"key"},
+	}
+	assert.Nil(invalidator.Publish(context.Background(), msg))
+	assert.Equal([]InvalidationMessage{msg}, messages)
+
+	unsubscribe()
+	assert.Nil(invalidator.Publish(context.Background(), msg))
+	assert.Equal(1, len(messages))
+}
+
+func TestL2CacheInvalidator(t *testing.T) {
+	assert := assert.New(t)
+
+	sc := &testBatchSlowCache{
+		testCountSlowCache: testCountSlowCache{
+			testSlowCache: testSlowCache{
+				data: make(map[string][]byte),
+			},
+		},
+	}
+	invalidator := NewMemoryInvalidator()
+	newL2Cache := func() *L2Cache {
+		return NewL2Cache(sc, 10, time.Minute,
+			L2CachePrefixOption("prefix:"),
+			L2CacheInvalidatorOption(invalidator),
+		)
+	}
+	l2a := newL2Cache()
+	defer l2a.Close()
+	l2b := newL2Cache()
+	ctx := context.Background()
+
+	assert.Nil(l2a.SetBytes(ctx, "a", []byte("1")))
+	buf, err := l2b.GetBytes(ctx, "a")
+	assert.Nil(err)
+	assert.Equal([]byte("1"), buf)
+
+	// 其它实例更新数据后，清除本地lru
+	assert.Nil(l2a.Set(ctx, "a", 2))
+	_, ok := l2b.ttlCache.Peek("prefix:a")
+	assert.False(ok)
+	// 忽略自身发布的消息
+	_, ok = l2a.ttlCache.Peek("prefix:a")
+	assert.True(ok)
+	buf, err = l2b.GetBytes(ctx, "a")
+	assert.Nil(err)
+	assert.Equal([]byte("2"), buf)
+
+	_, err = l2a.Del(ctx, "a")
+	assert.Nil(err)
+	_, ok = l2b.ttlCache.Peek("prefix:a")
+	assert.False(ok)
+
+	// 批量操作
+	assert.Nil(l2b.SetBytes(ctx, "b", []byte("1")))
+	assert.Nil(l2b.SetBytes(ctx, "c", []byte("1")))
+	assert.Nil(l2a.MSet(ctx, map[string]interface{}{
+		"b": 2,
+	}))
+	_, ok = l2b.ttlCache.Peek("prefix:b")
+	assert.False(ok)
+	_, ok = l2b.ttlCache.Peek("prefix:c")
+	assert.True(ok)
+	_, err = l2a.MDel(ctx, "c")
+	assert.Nil(err)
+	_, ok = l2b.ttlCache.Peek("prefix:c")
+	assert.False(ok)
+
+	// 关闭后不再接收消息
+	l2b.Close()
+	assert.Nil(l2b.SetBytes(ctx, "d", []byte("1")))
+	assert.Nil(l2a.SetBytes(ctx, "d", []byte("2")))
+	_, ok = l2b.ttlCache.Peek("prefix:d")
+	assert.True(ok)
+}
+
+type testFailedInvalidator struct {
+	*MemoryInvalidator
+}
+
+func (*testFailedInvalidator) Publish(_ context.Context, _ InvalidationMessage) error {
+	return errors.New("publish fail")
+}
+
+func TestL2CachePublishError(t *testing.T) {
+	assert := assert.New(t)
+
+	sc := &testCountSlowCache{
+		testSlowCache: testSlowCache{
+			data: make(map[string][]byte),
+		},
+	}
+	messages := make([]InvalidationMessage, 0)
+	l2 := NewL2Cache(sc, 10, time.Minute,
+		L2CacheInvalidatorOption(&testFailedInvalidator{
+			MemoryInvalidator: NewMemoryInvalidator(),
+		}),
+		L2CachePublishErrorOption(func(msg InvalidationMessage, err error) {
+			assert.Equal("publish fail", err.Error())
+			messages = append(messages, msg)
+		}),
+	)
+	defer l2.Close()
+	ctx := context.Background()
+
+	// 发布失败不影响写入
+	assert.Nil(l2.SetBytes(ctx, "a", []byte("1")))
+	assert.Nil(l2.Set(ctx, "b", 1))
+	_, err := l2.Del(ctx, "b")
+	assert.Nil(err)
+	assert.Nil(l2.MSet(ctx, map[string]interface{}{
+		"c": 1,
+	}))
+	_, err = l2.MDel(ctx, "c")
+	assert.Nil(err)
+	buf, err := l2.GetBytes(ctx, "a")
+	assert.Nil(err)
+	assert.Equal([]byte("1"), buf)
+
+	assert.Equal(5, len(messages))
+	assert.Equal([]string{"a"}, messages[0].Keys)
+}
@@ -0,0 +1,88 @@
+// Copyright 2022 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lruttl
+
+import (
+	"sync"
+	"time"
+)
+
+// expiredPurger is the cache which can purge expired items
+type expiredPurger interface {
+	lruKeys() []interface{}
+	purgeExpired(keys []interface{}) int
+}
+
+// janitor purges the expired items of cache in background
+type janitor struct {
+	interval   time.Duration
+	sampleSize int
+	cache      expiredPurger
+	// keys is the remaining keys of the current sampled sweep
+	keys     []interface{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+func newJanitor(interval time.Duration, sampleSize int, cache expiredPurger) *janitor {
+	return &janitor{
+		interval:   interval,
+		sampleSize: sampleSize,
+		cache:      cache,
+		done:       make(chan struct{}),
+	}
+}
+
+// run purges the expired items each interval until it is stopped
+func (j *janitor) run() {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-j.done:
+			return
+		case <-ticker.C:
+			j.sweep()
+		}
+	}
+}
+
+// sweep checks the items of cache and purges the expired items.
+// It returns the count of purged items.
+func (j *janitor) sweep() int {
+	// 全量检查
+	if j.sampleSize <= 0 {
+		return j.cache.purgeExpired(j.cache.lruKeys())
+	}
+	// 分批检查，每次只检查sampleSize个元素，
+	// 避免缓存较大时长时间锁定
+	if len(j.keys) == 0 {
+		j.keys = j.cache.lruKeys()
+	}
+	size := j.sampleSize
+	if size > len(j.keys) {
+		size = len(j.keys)
+	}
+	keys := j.keys[:size]
+	j.keys = j.keys[size:]
+	return j.cache.purgeExpired(keys)
+}
+
+// stop stops the janitor, it can be called more than once
+func (j *janitor) stop() {
+	j.stopOnce.Do(func() {
+		close(j.done)
+	})
+}
@@ -0,0 +1,72 @@
+package lruttl
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJanitor(t *testing.T) {
+	assert := assert.New(t)
+
+	mu := sync.Mutex{}
+	evictedKeys := make([]Key, 0)
+	cache := New(10, time.Minute, CacheJanitorOption(20*time.Millisecond, 0), CacheEvictedOption(func(key Key, value interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		evictedKeys = append(evictedKeys, key)
+	}))
+	defer cache.Close()
+
+	cache.Add("a", 1, 10*time.Millisecond)
+	cache.Add("b", 2)
+	assert.Equal(2, cache.Len())
+	time.Sleep(100 * time.Millisecond)
+
+	// 过期数据被清除并触发evicted
+	assert.Equal(1, cache.Len())
+	assert.Equal([]Key{"b"}, cache.Keys())
+	mu.Lock()
+	assert.Equal([]Key{"a"}, evictedKeys)
+	mu.Unlock()
+}
+
+func TestJanitorSampledSweep(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := New(10, time.Minute)
+	for i := 0; i < 5; i++ {
+		cache.Add(i, i, time.Nanosecond)
+	}
+	cache.Add(5, 5)
+	time.Sleep(time.Millisecond)
+
+	j := newJanitor(time.Second, 2, cache)
+	assert.Equal(2, j.sweep())
+	assert.Equal(4, cache.Len())
+	assert.Equal(2, j.sweep())
+	// 最后一批包括未过期的元素
+	assert.Equal(1, j.sweep())
+	assert.Equal(1, cache.Len())
+	assert.Equal(0, j.sweep())
+
+	// 重复调用stop不会出错
+	j.stop()
+	j.stop()
+}
+
+func TestJanitorKeepStale(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := New(10, time.Minute, CacheStaleOption(time.Minute, func(key Key) (interface{}, time.Duration, error) {
+		return nil, 0, nil
+	}))
+	cache.Add("a", 1, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	j := newJanitor(time.Second, 0, cache)
+	// 宽限期内不清除
+	assert.Equal(0, j.sweep())
+	assert.Equal(1, cache.Len())
+}
@@ -0,0 +1,75 @@
+// Copyright 2022 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lruttl
+
+import "time"
+
+// ttlJitter randomizes the ttl, so the items added at the same time
+// will not expire at the same time
+type ttlJitter struct {
+	// ratio is the max jitter ratio of ttl
+	ratio float64
+	// max is the max jitter duration
+	max time.Duration
+}
+
+// apply returns the ttl which is reduced by a random duration,
+// the ratio is used if it is set, otherwise the max duration is used.
+func (j ttlJitter) apply(ttl time.Duration) time.Duration {
+	d := j.max
+	if j.ratio > 0 {
+		d = time.Duration(float64(ttl) * j.ratio)
+	}
+	if d <= 0 || ttl <= 0 {
+		return ttl
+	}
+	// 保证ttl大于0
+	if d >= ttl {
+		d = ttl - 1
+	}
+	return ttl - time.Duration(float64(d)*float64(FastRand())/(1<<32))
+}
+
+// CacheTTLJitterOption sets the jitter ratio of ttl, the ttl of each Add will be
+// reduced randomly within the ratio, e.g. 0.1 means the ttl is in (90%, 100%] of ttl.
+func CacheTTLJitterOption(ratio float64) CacheOption {
+	return func(opts *cacheOptions) {
+		opts.jitter.ratio = ratio
+	}
+}
+
+// CacheTTLJitterDurationOption sets the max jitter duration of ttl,
+// the ttl of each Add will be reduced randomly within the duration.
+func CacheTTLJitterDurationOption(d time.Duration) CacheOption {
+	return func(opts *cacheOptions) {
+		opts.jitter.max = d
+	}
+}
+
+// L2CacheTTLJitterOption sets the jitter ratio of ttl for l2cache,
+// it is applied to both lru cache and slow cache.
+func L2CacheTTLJitterOption(ratio float64) L2CacheOption {
+	return func(c *L2Cache) {
+		c.jitter.ratio = ratio
+	}
+}
+
+// L2CacheTTLJitterDurationOption sets the max jitter duration of ttl for l2cache,
+// it is applied to both lru cache and slow cache.
+func L2CacheTTLJitterDurationOption(d time.Duration) L2CacheOption {
+	return func(c *L2Cache) {
+		c.jitter.max = d
+	}
+}
@@ -0,0 +1,72 @@
+package lruttl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTTLJitter(t *testing.T) {
+	assert := assert.New(t)
+
+	// 未设置则不调整
+	assert.Equal(time.Second, ttlJitter{}.apply(time.Second))
+
+	j := ttlJitter{
+		ratio: 0.1,
+	}
+	values := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		ttl := j.apply(time.Second)
+		assert.True(ttl > 900*time.Millisecond && ttl <= time.Second)
+		values[ttl] = true
+	}
+	assert.True(len(values) > 1)
+
+	j = ttlJitter{
+		max: time.Minute,
+	}
+	for i := 0; i < 100; i++ {
+		ttl := j.apply(time.Second)
+		// ttl需要大于0
+		assert.True(ttl > 0 && ttl <= time.Second)
+	}
+}
+
+func TestCacheTTLJitter(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := New(10, time.Minute, CacheTTLJitterDurationOption(10*time.Second))
+	for i := 0; i < 10; i++ {
+		cache.Add(i, i)
+		ttl := cache.TTL(i)
+		assert.True(ttl > 50*time.Second && ttl <= time.Minute)
+	}
+
+	cache = New(10, time.Minute, CacheTTLJitterOption(0.5))
+	cache.Add("a", 1, 10*time.Second)
+	ttl := cache.TTL("a")
+	assert.True(ttl > 5*time.Second && ttl <= 10*time.Second)
+}
+
+func TestL2CacheTTLJitter(t *testing.T) {
+	assert := assert.New(t)
+
+	sc := testSlowCache{
+		data: make(map[string][]byte),
+	}
+	ctx := context.Background()
+	l2 := NewL2Cache(&sc, 10, 10*time.Second, L2CacheTTLJitterOption(0.5))
+	err := l2.Set(ctx, "a", "1")
+	assert.Nil(err)
+	ttl := l2.ttlCache.TTL("a")
+	assert.True(ttl > 5*time.Second && ttl <= 10*time.Second)
+
+	l2 = NewL2Cache(&sc, 10, 10*time.Second, L2CacheTTLJitterDurationOption(time.Second))
+	err = l2.Set(ctx, "a", "1")
+	assert.Nil(err)
+	ttl = l2.ttlCache.TTL("a")
+	assert.True(ttl > 9*time.Second && ttl <= 10*time.Second)
+}
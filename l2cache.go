@@ -39,6 +39,10 @@ type L2CacheOption func(c *L2Cache)
 type L2CacheMarshal func(v interface{}) ([]byte, error)
 type L2CacheUnmarshal func(data []byte, v interface{}) error
 
+// L2CacheLoader loads the value of key when it does not exist in cache,
+// the ttl will be the default ttl of l2cache if it is 0.
+type L2CacheLoader func(ctx context.Context) (interface{}, time.Duration, error)
+
 // A l2cache for frequently visited  data
 type L2Cache struct {
 	// prefix is the prefix of all key, it will auto prepend to the key
@@ -57,6 +61,26 @@ type L2Cache struct {
 	unmarshal L2CacheUnmarshal
 
 	nilErr error
+	// negativeTTL is the ttl of not found marker, it is disabled if 0
+	negativeTTL time.Duration
+	// jitter randomizes the ttl of set
+	jitter ttlJitter
+	// clock is the clock of lru cache expiration
+	clock Clock
+	// flight merges the concurrent reads of slow cache
+	flight flightGroup[string, []byte]
+	// fetchTimeout is the timeout of the shared call, it is not canceled by any caller
+	fetchTimeout time.Duration
+	// loadFlight merges the concurrent loads of loader
+	loadFlight flightGroup[string, []byte]
+	// id is the origin of invalidation messages
+	id string
+	// invalidator publishes the invalidation of keys to the other instances
+	invalidator Invalidator
+	// unsubscribe stops receiving the invalidation messages
+	unsubscribe func()
+	// onPublishError is called when publishing the invalidation fails
+	onPublishError func(msg InvalidationMessage, err error)
 }
 
 // ErrIsNil is the error of nil cache
@@ -68,6 +92,15 @@ var ErrKeyIsNil = errors.New("key is nil")
 // ErrInvalidType is the error of invalid type
 var ErrInvalidType = errors.New("invalid type")
 
+// negativeMarker is the data of not found marker
+var negativeMarker = []byte("\x00lru-ttl:negative\x00")
+
+// notFoundTTL is the ttl of key which is not found, the same as Cache.TTL
+const notFoundTTL = time.Duration(-2)
+
+// defaultFetchTimeout is the default timeout of the shared call of slow cache and loader
+const defaultFetchTimeout = 10 * time.Second
+
 // BufferMarshal converts *bytes.Buffer to bytes,
 // it returns a ErrInvalidType if restult is not *bytes.Buffer
 func BufferMarshal(result interface{}) ([]byte, error) {
@@ -96,13 +129,22 @@ func NewL2Cache(slowCache SlowCache, maxEntries int, defaultTTL time.Duration, o
 		panic("default ttl should be gt one second")
 	}
 	c := &L2Cache{
-		ttl:       defaultTTL,
-		ttlCache:  New(maxEntries, defaultTTL),
-		slowCache: slowCache,
+		ttl:          defaultTTL,
+		slowCache:    slowCache,
+		fetchTimeout: defaultFetchTimeout,
 	}
 	for _, opt := range opts {
 		opt(c)
 	}
+	cacheOpts := make([]CacheOption, 0)
+	if c.clock != nil {
+		cacheOpts = append(cacheOpts, CacheClockOption(c.clock))
+	}
+	c.ttlCache = New(maxEntries, defaultTTL, cacheOpts...)
+	if c.invalidator != nil {
+		c.id = newInvalidationOrigin()
+		c.unsubscribe = c.invalidator.Subscribe(c.invalidate)
+	}
 	return c
 }
 
@@ -134,6 +176,38 @@ func L2CacheNilErrOption(nilErr error) L2CacheOption {
 	}
 }
 
+// L2CacheFetchTimeoutOption sets the timeout of the shared call of slow cache and
+// the loader of GetOrLoad, which is merged from the concurrent calls and not canceled by any caller.
+// It is 10 seconds by default, and 0 means no timeout.
+func L2CacheFetchTimeoutOption(timeout time.Duration) L2CacheOption {
+	return func(c *L2Cache) {
+		c.fetchTimeout = timeout
+	}
+}
+
+// L2CacheNegativeTTLOption sets the ttl of not found marker for l2cache.
+// If the loader of GetOrLoad returns the nil error (ErrIsNil if nil error is not set),
+// a not found marker will be set to lru cache and slow cache, and the nil error
+// will be returned directly without calling slow cache or loader until it is expired.
+func L2CacheNegativeTTLOption(ttl time.Duration) L2CacheOption {
+	return func(c *L2Cache) {
+		c.negativeTTL = ttl
+	}
+}
+
+// getNilErr returns the nil error of l2cache, it will be ErrIsNil if not set
+func (l2 *L2Cache) getNilErr() error {
+	if l2.nilErr != nil {
+		return l2.nilErr
+	}
+	return ErrIsNil
+}
+
+// isNegative returns true if the data is not found marker
+func isNegative(buf []byte) bool {
+	return bytes.Equal(buf, negativeMarker)
+}
+
 func (l2 *L2Cache) getKey(key string) (string, error) {
 	if key == "" {
 		return "", ErrKeyIsNil
@@ -141,7 +215,8 @@ func (l2 *L2Cache) getKey(key string) (string, error) {
 	return l2.prefix + key, nil
 }
 
-// TTL returns the ttl for key
+// TTL returns the ttl for key, it returns -2 if the key is cached as not found in lru cache.
+// The ttl of slow cache is returned directly, the not found marker in slow cache is not checked.
 func (l2 *L2Cache) TTL(ctx context.Context, key string) (time.Duration, error) {
 	key, err := l2.getKey(key)
 	if err != nil {
@@ -152,6 +227,10 @@ func (l2 *L2Cache) TTL(ctx context.Context, key string) (time.Duration, error) {
 	// 由于lru有大小限制，可能由于空间不够导致不存在
 	// 不存在时则从slow cache获取
 	if d >= 0 {
+		// 不存在的标记
+		if buf, _ := l2.ttlCache.PeekBytes(key); isNegative(buf) {
+			return notFoundTTL, nil
+		}
 		return d, nil
 	}
 	return l2.slowCache.TTL(ctx, key)
@@ -172,22 +251,34 @@ func (l2 *L2Cache) getBytes(ctx context.Context, key string) ([]byte, error) {
 	// 有可能数据未过期但lru空间较小，因此被删除
 	// 也有可能lru中数据过期但 slow cache中数据已更新
 	if len(buf) == 0 {
-		b, err := l2.slowCache.Get(ctx, key)
+		return l2.fetchBytes(ctx, key)
+	}
+	return buf, nil
+}
+
+// fetchBytes gets data from slow cache and sets it to lru cache,
+// the concurrent calls of the same key are merged into one,
+// and the waiters share the result of the first call.
+func (l2 *L2Cache) fetchBytes(ctx context.Context, key string) ([]byte, error) {
+	return l2.flight.doContext(ctx, key, func() ([]byte, error) {
+		// 共享的调用不能被某个调用者的context取消，但需要超时限制
+		sharedCtx, cancel := detachContext(ctx, l2.fetchTimeout)
+		defer cancel()
+		buf, err := l2.slowCache.Get(sharedCtx, key)
 		if err != nil {
 			return nil, err
 		}
-		buf = b
 		// 成功从slowcache获取缓存，则将数据设置回lru ttl
 		if len(buf) != 0 {
 			// 获取ttl失败时忽略不设置lru cache即可
 			// 因此忽略错误
-			ttl, _ := l2.slowCache.TTL(ctx, key)
+			ttl, _ := l2.slowCache.TTL(sharedCtx, key)
 			if ttl != 0 {
 				l2.ttlCache.Add(key, buf, ttl)
 			}
 		}
-	}
-	return buf, nil
+		return buf, nil
+	})
 }
 
 // GetBytes gets data from lur cache first, if not exists,
@@ -198,7 +289,15 @@ func (l2 *L2Cache) GetBytes(ctx context.Context, key string) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	return l2.getBytes(ctx, key)
+	buf, err := l2.getBytes(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	// 不存在的标记返回nil error
+	if isNegative(buf) {
+		return nil, l2.getNilErr()
+	}
+	return buf, nil
 }
 
 // setBytes sets data to lru cache and slow cache
@@ -207,6 +306,8 @@ func (l2 *L2Cache) setBytes(ctx context.Context, key string, value []byte, ttl .
 	if len(ttl) != 0 && ttl[0] != 0 {
 		t = ttl[0]
 	}
+	// 两级缓存使用相同的ttl
+	t = l2.jitter.apply(t)
 	// 先设置较慢的缓存
 	err := l2.slowCache.Set(ctx, key, value, t)
 	if err != nil {
@@ -223,7 +324,12 @@ func (l2 *L2Cache) SetBytes(ctx context.Context, key string, value []byte, ttl .
 	if err != nil {
 		return err
 	}
-	return l2.setBytes(ctx, key, value, ttl...)
+	err = l2.setBytes(ctx, key, value, ttl...)
+	if err != nil {
+		return err
+	}
+	l2.publish(ctx, key)
+	return nil
 }
 
 // Get gets data from lru cache first, if not exists,
@@ -239,7 +345,8 @@ func (l2 *L2Cache) Get(ctx context.Context, key string, result interface{}) erro
 // It will not return nil error.
 func (l2 *L2Cache) GetIgnoreNilErr(ctx context.Context, key string, result interface{}) error {
 	err := l2.get(ctx, key, result)
-	if err != nil && err == l2.nilErr {
+	// 未设置nil error时，不存在的标记返回ErrIsNil
+	if err != nil && errors.Is(err, l2.getNilErr()) {
 		err = nil
 	}
 	return err
@@ -255,16 +362,78 @@ func (l2 *L2Cache) get(ctx context.Context, key string, result interface{}) erro
 	if err != nil {
 		return err
 	}
+	// 不存在的标记返回nil error
+	if isNegative(buf) {
+		return l2.getNilErr()
+	}
+	return l2.doUnmarshal(buf, result)
+}
 
+func (l2 *L2Cache) doMarshal(value interface{}) ([]byte, error) {
+	fn := l2.marshal
+	if fn == nil {
+		fn = json.Marshal
+	}
+	return fn(value)
+}
+
+func (l2 *L2Cache) doUnmarshal(buf []byte, result interface{}) error {
 	fn := l2.unmarshal
 	if fn == nil {
 		fn = json.Unmarshal
 	}
-	err = fn(buf, result)
+	return fn(buf, result)
+}
+
+// GetOrLoad gets data from lru cache first, then gets the data from slow cache,
+// if both of them do not exist, the loader will be called and the value will be
+// set to lru cache and slow cache. The concurrent loads of the same key are merged into one,
+// the loader is called with a context which is not canceled by any caller but bounded by
+// the fetch timeout (see L2CacheFetchTimeoutOption), and each caller
+// returns the error of its own context if it is done before the load completes.
+// The error of slow cache is returned directly unless it is the nil error.
+func (l2 *L2Cache) GetOrLoad(ctx context.Context, key string, result interface{}, loader L2CacheLoader) error {
+	key, err := l2.getKey(key)
 	if err != nil {
 		return err
 	}
-	return nil
+	buf, err := l2.getBytes(ctx, key)
+	// nil error表示数据不存在，需要通过loader加载
+	if err != nil && (l2.nilErr == nil || !errors.Is(err, l2.nilErr)) {
+		return err
+	}
+	if len(buf) == 0 {
+		buf, err = l2.loadFlight.doContext(ctx, key, func() ([]byte, error) {
+			// 共享的加载不能被某个调用者的context取消，但需要超时限制
+			sharedCtx, cancel := detachContext(ctx, l2.fetchTimeout)
+			defer cancel()
+			value, ttl, err := loader(sharedCtx)
+			// 数据不存在，设置不存在的标记
+			if err != nil && l2.negativeTTL > 0 && errors.Is(err, l2.getNilErr()) {
+				_ = l2.setBytes(sharedCtx, key, negativeMarker, l2.negativeTTL)
+				return negativeMarker, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			buf, err := l2.doMarshal(value)
+			if err != nil {
+				return nil, err
+			}
+			err = l2.setBytes(sharedCtx, key, buf, ttl)
+			if err != nil {
+				return nil, err
+			}
+			return buf, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if isNegative(buf) {
+		return l2.getNilErr()
+	}
+	return l2.doUnmarshal(buf, result)
 }
 
 // Set converts the value to bytes, then sets it to lru cache and slow cache
@@ -273,15 +442,16 @@ func (l2 *L2Cache) Set(ctx context.Context, key string, value interface{}, ttl .
 	if err != nil {
 		return err
 	}
-	fn := l2.marshal
-	if fn == nil {
-		fn = json.Marshal
+	buf, err := l2.doMarshal(value)
+	if err != nil {
+		return err
 	}
-	buf, err := fn(value)
+	err = l2.setBytes(ctx, key, buf, ttl...)
 	if err != nil {
 		return err
 	}
-	return l2.setBytes(ctx, key, buf, ttl...)
+	l2.publish(ctx, key)
+	return nil
 }
 
 // Del deletes data from lru cache and slow cache
@@ -292,5 +462,10 @@ func (l2 *L2Cache) Del(ctx context.Context, key string) (int64, error) {
 	}
 	// 先清除ttl cache
 	l2.ttlCache.Remove(key)
-	return l2.slowCache.Del(ctx, key)
+	count, err := l2.slowCache.Del(ctx, key)
+	if err != nil {
+		return count, err
+	}
+	l2.publish(ctx, key)
+	return count, nil
 }
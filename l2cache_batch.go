@@ -0,0 +1,240 @@
+// Copyright 2022 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lruttl
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+)
+
+// ErrBatchResultMismatch is the error of batch slow cache returns the result which does not match keys
+var ErrBatchResultMismatch = errors.New("batch result does not match keys")
+
+// BatchSlowCache is the optional interface of slow cache for batch operations,
+// the batch functions of l2cache will fall back to per-key calls if it is not implemented.
+type BatchSlowCache interface {
+	// MGet returns the data and ttl of keys in the same order,
+	// the data should be nil if the key does not exist.
+	MGet(ctx context.Context, keys ...string) ([][]byte, []time.Duration, error)
+	// MSet sets the data of keys with the ttl of each key
+	MSet(ctx context.Context, values map[string][]byte, ttls map[string]time.Duration) error
+	// MDel deletes the keys and returns the count of deleted keys
+	MDel(ctx context.Context, keys ...string) (int64, error)
+}
+
+// MGetBytes gets data of keys from lru cache first, the missing keys
+// are fetched from slow cache. The keys which do not exist are not
+// in the result map.
+func (l2 *L2Cache) MGetBytes(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	result := make(map[string][]byte, len(keys))
+	misses := make([]string, 0)
+	// 记录添加前缀后的key对应的原始key
+	originalKeys := make(map[string]string, len(keys))
+	for _, key := range keys {
+		k, err := l2.getKey(key)
+		if err != nil {
+			return nil, err
+		}
+		originalKeys[k] = key
+		v, ok := l2.ttlCache.Get(k)
+		var buf []byte
+		if ok && v != nil {
+			buf, _ = v.([]byte)
+		}
+		if len(buf) == 0 {
+			misses = append(misses, k)
+			continue
+		}
+		if !isNegative(buf) {
+			result[key] = buf
+		}
+	}
+	if len(misses) == 0 {
+		return result, nil
+	}
+
+	values, err := l2.mgetSlowBytes(ctx, misses)
+	if err != nil {
+		return nil, err
+	}
+	for i, buf := range values {
+		if len(buf) == 0 || isNegative(buf) {
+			continue
+		}
+		result[originalKeys[misses[i]]] = buf
+	}
+	return result, nil
+}
+
+// mgetSlowBytes gets data of keys from slow cache and sets them to lru cache
+func (l2 *L2Cache) mgetSlowBytes(ctx context.Context, keys []string) ([][]byte, error) {
+	batch, ok := l2.slowCache.(BatchSlowCache)
+	// 不支持批量操作则逐个获取
+	if !ok {
+		values := make([][]byte, len(keys))
+		for i, key := range keys {
+			buf, err := l2.fetchBytes(ctx, key)
+			// 不存在的数据忽略
+			if err != nil && (l2.nilErr == nil || !errors.Is(err, l2.nilErr)) {
+				return nil, err
+			}
+			values[i] = buf
+		}
+		return values, nil
+	}
+	values, ttls, err := batch.MGet(ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != len(keys) || len(ttls) != len(keys) {
+		return nil, ErrBatchResultMismatch
+	}
+	for i, buf := range values {
+		// 成功获取的数据设置回lru ttl
+		if len(buf) != 0 && ttls[i] != 0 {
+			l2.ttlCache.Add(keys[i], buf, ttls[i])
+		}
+	}
+	return values, nil
+}
+
+// MGet gets data of keys from lru cache first, the missing keys are fetched
+// from slow cache. The key of results is the cache key, and the value is the
+// result which data will be unmarshaled to. It returns the keys which do not exist.
+func (l2 *L2Cache) MGet(ctx context.Context, results map[string]interface{}) ([]string, error) {
+	keys := make([]string, 0, len(results))
+	for key := range results {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	values, err := l2.MGetBytes(ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+	missingKeys := make([]string, 0)
+	for _, key := range keys {
+		buf, ok := values[key]
+		if !ok {
+			missingKeys = append(missingKeys, key)
+			continue
+		}
+		err = l2.doUnmarshal(buf, results[key])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return missingKeys, nil
+}
+
+// MSet converts the values to bytes, then sets them to lru cache and slow cache
+func (l2 *L2Cache) MSet(ctx context.Context, values map[string]interface{}, ttl ...time.Duration) error {
+	data := make(map[string][]byte, len(values))
+	for key, value := range values {
+		k, err := l2.getKey(key)
+		if err != nil {
+			return err
+		}
+		buf, err := l2.doMarshal(value)
+		if err != nil {
+			return err
+		}
+		data[k] = buf
+	}
+	err := l2.msetBytes(ctx, data, ttl...)
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	l2.publish(ctx, keys...)
+	return nil
+}
+
+// msetBytes sets the data of prefixed keys to lru cache and slow cache
+func (l2 *L2Cache) msetBytes(ctx context.Context, data map[string][]byte, ttl ...time.Duration) error {
+	batch, ok := l2.slowCache.(BatchSlowCache)
+	// 不支持批量操作则逐个设置
+	if !ok {
+		for key, buf := range data {
+			err := l2.setBytes(ctx, key, buf, ttl...)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	t := l2.ttl
+	if len(ttl) != 0 && ttl[0] != 0 {
+		t = ttl[0]
+	}
+	// 每个key的ttl单独随机，避免同时过期
+	ttls := make(map[string]time.Duration, len(data))
+	for key := range data {
+		ttls[key] = l2.jitter.apply(t)
+	}
+	// 先设置较慢的缓存
+	err := batch.MSet(ctx, data, ttls)
+	if err != nil {
+		return err
+	}
+	for key, buf := range data {
+		l2.ttlCache.Add(key, buf, ttls[key])
+	}
+	return nil
+}
+
+// MDel deletes data of keys from lru cache and slow cache
+func (l2 *L2Cache) MDel(ctx context.Context, keys ...string) (int64, error) {
+	prefixedKeys := make([]string, len(keys))
+	for i, key := range keys {
+		k, err := l2.getKey(key)
+		if err != nil {
+			return 0, err
+		}
+		prefixedKeys[i] = k
+	}
+	count, err := l2.mdel(ctx, prefixedKeys)
+	if err != nil {
+		return count, err
+	}
+	l2.publish(ctx, prefixedKeys...)
+	return count, nil
+}
+
+// mdel deletes data of prefixed keys from lru cache and slow cache
+func (l2 *L2Cache) mdel(ctx context.Context, keys []string) (int64, error) {
+	// 先清除ttl cache
+	for _, key := range keys {
+		l2.ttlCache.Remove(key)
+	}
+	batch, ok := l2.slowCache.(BatchSlowCache)
+	if ok {
+		return batch.MDel(ctx, keys...)
+	}
+	// 不支持批量操作则逐个删除
+	var count int64
+	for _, key := range keys {
+		n, err := l2.slowCache.Del(ctx, key)
+		if err != nil {
+			return count, err
+		}
+		count += n
+	}
+	return count, nil
+}
@@ -0,0 +1,198 @@
+package lruttl
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testBatchSlowCache struct {
+	testCountSlowCache
+	mgetCount int32
+	msetCount int32
+	mdelCount int32
+	// mgetKeys is the keys of last MGet
+	mgetKeys []string
+	// msetTTLs is the ttls of last MSet
+	msetTTLs map[string]time.Duration
+}
+
+func (sc *testBatchSlowCache) MGet(_ context.Context, keys ...string) ([][]byte, []time.Duration, error) {
+	atomic.AddInt32(&sc.mgetCount, 1)
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.mgetKeys = keys
+	values := make([][]byte, len(keys))
+	ttls := make([]time.Duration, len(keys))
+	for i, key := range keys {
+		buf, ok := sc.data[key]
+		if ok {
+			values[i] = buf
+			ttls[i] = slowCacheTTL
+		}
+	}
+	return values, ttls, nil
+}
+
+func (sc *testBatchSlowCache) MSet(_ context.Context, values map[string][]byte, ttls map[string]time.Duration) error {
+	atomic.AddInt32(&sc.msetCount, 1)
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.msetTTLs = ttls
+	for key, value := range values {
+		sc.data[key] = value
+	}
+	return nil
+}
+
+func (sc *testBatchSlowCache) MDel(_ context.Context, keys ...string) (int64, error) {
+	atomic.AddInt32(&sc.mdelCount, 1)
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	var count int64
+	for _, key := range keys {
+		if _, ok := sc.data[key]; ok {
+			delete(sc.data, key)
+			count++
+		}
+	}
+	return count, nil
+}
+
+func TestL2CacheBatch(t *testing.T) {
+	assert := assert.New(t)
+
+	sc := &testBatchSlowCache{
+		testCountSlowCache: testCountSlowCache{
+			testSlowCache: testSlowCache{
+				data: map[string][]byte{
+					"prefix:c": []byte(`{"name":"c"}`),
+				},
+			},
+		},
+	}
+	l2 := NewL2Cache(sc, 10, time.Minute,
+		L2CachePrefixOption("prefix:"),
+		L2CacheNilErrOption(testSlowCacheNilErr),
+	)
+	ctx := context.Background()
+
+	err := l2.MSet(ctx, map[string]interface{}{
+		"a": &testData{Name: "a"},
+		"b": &testData{Name: "b"},
+	})
+	assert.Nil(err)
+	assert.Equal(int32(1), atomic.LoadInt32(&sc.msetCount))
+	assert.Equal(time.Minute, l2.ttlCache.TTL("prefix:a").Round(time.Second))
+
+	// lru中存在的数据不从slow cache获取
+	values, err := l2.MGetBytes(ctx, "a", "b", "c", "d")
+	assert.Nil(err)
+	assert.Equal(map[string][]byte{
+		"a": []byte(`{"name":"a"}`),
+		"b": []byte(`{"name":"b"}`),
+		"c": []byte(`{"name":"c"}`),
+	}, values)
+	assert.Equal(int32(1), atomic.LoadInt32(&sc.mgetCount))
+	assert.Equal([]string{"prefix:c", "prefix:d"}, sc.mgetKeys)
+	assert.Equal(int32(0), atomic.LoadInt32(&sc.count))
+	// slow cache中获取的数据设置回lru
+	assert.True(l2.ttlCache.TTL("prefix:c") > 0)
+
+	a := testData{}
+	c := testData{}
+	d := testData{}
+	missingKeys, err := l2.MGet(ctx, map[string]interface{}{
+		"a": &a,
+		"c": &c,
+		"d": &d,
+	})
+	assert.Nil(err)
+	assert.Equal([]string{"d"}, missingKeys)
+	assert.Equal("a", a.Name)
+	assert.Equal("c", c.Name)
+
+	count, err := l2.MDel(ctx, "a", "b", "d")
+	assert.Nil(err)
+	assert.Equal(int64(2), count)
+	assert.Equal(int32(1), atomic.LoadInt32(&sc.mdelCount))
+	assert.Equal(time.Duration(-2), l2.ttlCache.TTL("prefix:a"))
+
+	_, err = l2.MGetBytes(ctx, "a", "")
+	assert.Equal(ErrKeyIsNil, err)
+}
+
+func TestL2CacheMSetTTLJitter(t *testing.T) {
+	assert := assert.New(t)
+
+	sc := &testBatchSlowCache{
+		testCountSlowCache: testCountSlowCache{
+			testSlowCache: testSlowCache{
+				data: make(map[string][]byte),
+			},
+		},
+	}
+	l2 := NewL2Cache(sc, 100, time.Minute, L2CacheTTLJitterOption(0.5))
+	values := make(map[string]interface{})
+	for i := 0; i < 50; i++ {
+		values[strconv.Itoa(i)] = i
+	}
+	assert.Nil(l2.MSet(context.Background(), values))
+
+	// 每个key的ttl单独随机
+	sc.mu.Lock()
+	ttls := sc.msetTTLs
+	sc.mu.Unlock()
+	assert.Equal(50, len(ttls))
+	distinct := make(map[time.Duration]bool)
+	for key, ttl := range ttls {
+		assert.True(ttl > 30*time.Second && ttl <= time.Minute)
+		lruTTL := l2.ttlCache.TTL(key)
+		assert.True(lruTTL > 0 && lruTTL <= ttl)
+		distinct[ttl] = true
+	}
+	assert.True(len(distinct) > 1)
+}
+
+func TestL2CacheBatchFallback(t *testing.T) {
+	assert := assert.New(t)
+
+	sc := &testCountSlowCache{
+		testSlowCache: testSlowCache{
+			data: map[string][]byte{
+				"c": []byte(`{"name":"c"}`),
+			},
+		},
+	}
+	l2 := NewL2Cache(sc, 10, time.Minute, L2CacheNilErrOption(testSlowCacheNilErr))
+	ctx := context.Background()
+
+	err := l2.MSet(ctx, map[string]interface{}{
+		"a": &testData{Name: "a"},
+		"b": &testData{Name: "b"},
+	})
+	assert.Nil(err)
+	sc.mu.Lock()
+	assert.Equal(3, len(sc.data))
+	sc.mu.Unlock()
+
+	values, err := l2.MGetBytes(ctx, "a", "c", "d")
+	assert.Nil(err)
+	assert.Equal(map[string][]byte{
+		"a": []byte(`{"name":"a"}`),
+		"c": []byte(`{"name":"c"}`),
+	}, values)
+	// 只有未命中lru的数据逐个从slow cache获取
+	assert.Equal(int32(2), atomic.LoadInt32(&sc.count))
+
+	count, err := l2.MDel(ctx, "a", "b", "c")
+	assert.Nil(err)
+	assert.Equal(int64(3), count)
+	sc.mu.Lock()
+	assert.Equal(0, len(sc.data))
+	sc.mu.Unlock()
+}
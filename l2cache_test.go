@@ -18,6 +18,8 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -209,3 +211,374 @@ func TestBufferMarshalUnmarshal(t *testing.T) {
 	assert.Nil(err)
 	assert.Equal(buf, newBuf)
 }
+
+type testCountSlowCache struct {
+	testSlowCache
+	mu    sync.Mutex
+	delay time.Duration
+	count int32
+	// emptyOnMiss returns empty data without error if not found
+	emptyOnMiss bool
+}
+
+func (sc *testCountSlowCache) Get(_ context.Context, key string) ([]byte, error) {
+	atomic.AddInt32(&sc.count, 1)
+	time.Sleep(sc.delay)
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	buf, ok := sc.data[key]
+	if !ok && !sc.emptyOnMiss {
+		return nil, testSlowCacheNilErr
+	}
+	return buf, nil
+}
+
+func (sc *testCountSlowCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.testSlowCache.Set(ctx, key, value, ttl)
+}
+
+func TestL2CacheGetBytesSingleFlight(t *testing.T) {
+	assert := assert.New(t)
+
+	sc := &testCountSlowCache{
+		testSlowCache: testSlowCache{
+			data: map[string][]byte{
+				"a": []byte("1"),
+			},
+		},
+		delay: 100 * time.Millisecond,
+	}
+	l2 := NewL2Cache(sc, 10, time.Minute)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf, err := l2.GetBytes(context.Background(), "a")
+			assert.Nil(err)
+			assert.Equal([]byte("1"), buf)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(int32(1), atomic.LoadInt32(&sc.count))
+
+	// 等待者的context取消时直接返回
+	l2.ttlCache.Remove("a")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		buf, err := l2.GetBytes(context.Background(), "a")
+		assert.Nil(err)
+		assert.Equal([]byte("1"), buf)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := l2.GetBytes(ctx, "a")
+	assert.Equal(context.Canceled, err)
+	<-done
+	assert.Equal(int32(2), atomic.LoadInt32(&sc.count))
+}
+
+func TestL2CacheGetOrLoad(t *testing.T) {
+	assert := assert.New(t)
+
+	sc := &testCountSlowCache{
+		testSlowCache: testSlowCache{
+			data: map[string][]byte{
+				"b": []byte(`{"name":"b"}`),
+			},
+		},
+		delay: 10 * time.Millisecond,
+	}
+	l2 := NewL2Cache(sc, 10, time.Minute, L2CacheNilErrOption(testSlowCacheNilErr), L2CacheClockOption(NewFakeClock(time.Now())))
+	ctx := context.Background()
+
+	var loadCount int32
+	loader := func(ctx context.Context) (interface{}, time.Duration, error) {
+		atomic.AddInt32(&loadCount, 1)
+		time.Sleep(50 * time.Millisecond)
+		return &testData{
+			Name: "a",
+		}, 0, nil
+	}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := testData{}
+			err := l2.GetOrLoad(ctx, "a", &result, loader)
+			assert.Nil(err)
+			assert.Equal("a", result.Name)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(int32(1), atomic.LoadInt32(&loadCount))
+	// 加载的数据写入两级缓存
+	assert.Equal(time.Minute, l2.ttlCache.TTL("a"))
+	sc.mu.Lock()
+	assert.Equal([]byte(`{"name":"a"}`), sc.data["a"])
+	sc.mu.Unlock()
+
+	// slow cache中存在则不调用loader
+	result := testData{}
+	err := l2.GetOrLoad(ctx, "b", &result, loader)
+	assert.Nil(err)
+	assert.Equal("b", result.Name)
+	assert.Equal(int32(1), atomic.LoadInt32(&loadCount))
+
+	// loader出错
+	customErr := errors.New("custom error")
+	err = l2.GetOrLoad(ctx, "c", &result, func(ctx context.Context) (interface{}, time.Duration, error) {
+		return nil, 0, customErr
+	})
+	assert.Equal(customErr, err)
+
+	// 指定ttl
+	err = l2.GetOrLoad(ctx, "d", &result, func(ctx context.Context) (interface{}, time.Duration, error) {
+		return &testData{
+			Name: "d",
+		}, 2 * time.Minute, nil
+	})
+	assert.Nil(err)
+	assert.Equal("d", result.Name)
+	assert.Equal(2*time.Minute, l2.ttlCache.TTL("d"))
+
+	assert.Equal(ErrKeyIsNil, l2.GetOrLoad(ctx, "", &result, loader))
+}
+
+func TestL2CacheNegativeTTL(t *testing.T) {
+	assert := assert.New(t)
+
+	sc := &testCountSlowCache{
+		testSlowCache: testSlowCache{
+			data: make(map[string][]byte),
+		},
+	}
+	clock := NewFakeClock(time.Now())
+	l2 := NewL2Cache(sc, 10, time.Minute,
+		L2CacheNilErrOption(testSlowCacheNilErr),
+		L2CacheNegativeTTLOption(time.Second),
+		L2CacheClockOption(clock),
+	)
+	ctx := context.Background()
+
+	var loadCount int32
+	loader := func(ctx context.Context) (interface{}, time.Duration, error) {
+		atomic.AddInt32(&loadCount, 1)
+		return nil, 0, testSlowCacheNilErr
+	}
+	result := testData{}
+	err := l2.GetOrLoad(ctx, "a", &result, loader)
+	assert.Equal(testSlowCacheNilErr, err)
+	assert.Equal(int32(1), atomic.LoadInt32(&loadCount))
+	assert.Equal(int32(1), atomic.LoadInt32(&sc.count))
+	// 不存在的标记写入两级缓存
+	assert.Equal(time.Second, l2.ttlCache.TTL("a"))
+	sc.mu.Lock()
+	assert.Equal(negativeMarker, sc.data["a"])
+	sc.mu.Unlock()
+
+	// 标记未过期，不再调用slow cache与loader
+	err = l2.GetOrLoad(ctx, "a", &result, loader)
+	assert.Equal(testSlowCacheNilErr, err)
+	err = l2.Get(ctx, "a", &result)
+	assert.Equal(testSlowCacheNilErr, err)
+	assert.Nil(l2.GetIgnoreNilErr(ctx, "a", &result))
+	_, err = l2.GetBytes(ctx, "a")
+	assert.Equal(testSlowCacheNilErr, err)
+	assert.Equal(int32(1), atomic.LoadInt32(&loadCount))
+	assert.Equal(int32(1), atomic.LoadInt32(&sc.count))
+
+	ttl, err := l2.TTL(ctx, "a")
+	assert.Nil(err)
+	assert.Equal(time.Duration(-2), ttl)
+
+	// lru中的标记过期后，ttl直接从slow cache获取，不读取数据
+	clock.Advance(2 * time.Second)
+	ttl, err = l2.TTL(ctx, "a")
+	assert.Nil(err)
+	assert.Equal(slowCacheTTL, ttl)
+	assert.Equal(int32(1), atomic.LoadInt32(&sc.count))
+	// 从slow cache获取标记
+	err = l2.GetOrLoad(ctx, "a", &result, loader)
+	assert.Equal(testSlowCacheNilErr, err)
+	assert.Equal(int32(1), atomic.LoadInt32(&loadCount))
+	assert.Equal(int32(2), atomic.LoadInt32(&sc.count))
+
+	// 设置数据后覆盖标记
+	assert.Nil(l2.Set(ctx, "a", &testData{
+		Name: "a",
+	}))
+	err = l2.GetOrLoad(ctx, "a", &result, loader)
+	assert.Nil(err)
+	assert.Equal("a", result.Name)
+}
+
+func TestL2CacheNegativeTTLDefaultNilErr(t *testing.T) {
+	assert := assert.New(t)
+
+	sc := &testCountSlowCache{
+		testSlowCache: testSlowCache{
+			data: make(map[string][]byte),
+		},
+		emptyOnMiss: true,
+	}
+	l2 := NewL2Cache(sc, 10, time.Minute, L2CacheNegativeTTLOption(time.Second))
+	ctx := context.Background()
+	// 未设置nil error时，loader返回ErrIsNil表示不存在
+	err := l2.GetOrLoad(ctx, "a", &testData{}, func(ctx context.Context) (interface{}, time.Duration, error) {
+		return nil, 0, ErrIsNil
+	})
+	assert.Equal(ErrIsNil, err)
+	_, err = l2.GetBytes(ctx, "a")
+	assert.Equal(ErrIsNil, err)
+	assert.Nil(l2.GetIgnoreNilErr(ctx, "a", &testData{}))
+	ttl, err := l2.TTL(ctx, "a")
+	assert.Nil(err)
+	assert.Equal(time.Duration(-2), ttl)
+}
+
+func TestL2CacheGetBytesFirstCallerCanceled(t *testing.T) {
+	assert := assert.New(t)
+
+	sc := &testCountSlowCache{
+		testSlowCache: testSlowCache{
+			data: map[string][]byte{
+				"a": []byte("1"),
+			},
+		},
+		delay: 100 * time.Millisecond,
+	}
+	l2 := NewL2Cache(sc, 10, time.Minute)
+
+	// 第一个调用者的context超时，不影响其它等待者
+	done := make(chan error)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := l2.GetBytes(ctx, "a")
+		done <- err
+	}()
+	time.Sleep(5 * time.Millisecond)
+	buf, err := l2.GetBytes(context.Background(), "a")
+	assert.Nil(err)
+	assert.Equal([]byte("1"), buf)
+	assert.Equal(context.DeadlineExceeded, <-done)
+	assert.Equal(int32(1), atomic.LoadInt32(&sc.count))
+}
+
+// testBlockSlowCache blocks the get until the context is done
+type testBlockSlowCache struct {
+	testSlowCache
+	count    int32
+	deadline chan time.Time
+}
+
+func (sc *testBlockSlowCache) Get(ctx context.Context, key string) ([]byte, error) {
+	atomic.AddInt32(&sc.count, 1)
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return nil, errors.New("no deadline")
+	}
+	sc.deadline <- deadline
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestL2CacheFetchTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	sc := &testBlockSlowCache{
+		deadline: make(chan time.Time, 2),
+	}
+	l2 := NewL2Cache(sc, 10, time.Minute, L2CacheFetchTimeoutOption(20*time.Millisecond))
+	assert.Equal(defaultFetchTimeout, NewL2Cache(sc, 10, time.Minute).fetchTimeout)
+
+	// 共享的调用有超时限制，不会一直阻塞
+	start := time.Now()
+	_, err := l2.GetBytes(context.Background(), "a")
+	assert.Equal(context.DeadlineExceeded, err)
+	assert.True(time.Since(start) < time.Second)
+	deadline := <-sc.deadline
+	assert.WithinDuration(start.Add(20*time.Millisecond), deadline, 100*time.Millisecond)
+
+	// 超时后重新调用slow cache
+	_, err = l2.GetBytes(context.Background(), "a")
+	assert.Equal(context.DeadlineExceeded, err)
+	<-sc.deadline
+	assert.Equal(int32(2), atomic.LoadInt32(&sc.count))
+}
+
+func TestL2CacheGetOrLoadTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	sc := &testCountSlowCache{
+		testSlowCache: testSlowCache{
+			data: make(map[string][]byte),
+		},
+	}
+	l2 := NewL2Cache(sc, 10, time.Minute, L2CacheNilErrOption(testSlowCacheNilErr), L2CacheFetchTimeoutOption(20*time.Millisecond))
+
+	var loadCount int32
+	loader := func(ctx context.Context) (interface{}, time.Duration, error) {
+		atomic.AddInt32(&loadCount, 1)
+		_, ok := ctx.Deadline()
+		assert.True(ok)
+		<-ctx.Done()
+		return nil, 0, ctx.Err()
+	}
+	// 加载超时后返回出错，不会一直阻塞
+	start := time.Now()
+	err := l2.GetOrLoad(context.Background(), "a", &testData{}, loader)
+	assert.Equal(context.DeadlineExceeded, err)
+	assert.True(time.Since(start) < time.Second)
+
+	err = l2.GetOrLoad(context.Background(), "a", &testData{}, loader)
+	assert.Equal(context.DeadlineExceeded, err)
+	assert.Equal(int32(2), atomic.LoadInt32(&loadCount))
+}
+
+func TestL2CacheGetOrLoadFirstCallerCanceled(t *testing.T) {
+	assert := assert.New(t)
+
+	sc := &testCountSlowCache{
+		testSlowCache: testSlowCache{
+			data: make(map[string][]byte),
+		},
+	}
+	l2 := NewL2Cache(sc, 10, time.Minute, L2CacheNilErrOption(testSlowCacheNilErr))
+
+	var loadCount int32
+	loader := func(ctx context.Context) (interface{}, time.Duration, error) {
+		atomic.AddInt32(&loadCount, 1)
+		select {
+		case <-ctx.Done():
+			return nil, 0, ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+		return &testData{
+			Name: "a",
+		}, 0, nil
+	}
+	// 第一个调用者的context超时，不影响加载与其它等待者
+	done := make(chan error)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		done <- l2.GetOrLoad(ctx, "a", &testData{}, loader)
+	}()
+	time.Sleep(5 * time.Millisecond)
+	result := testData{}
+	err := l2.GetOrLoad(context.Background(), "a", &result, loader)
+	assert.Nil(err)
+	assert.Equal("a", result.Name)
+	assert.Equal(context.DeadlineExceeded, <-done)
+	assert.Equal(int32(1), atomic.LoadInt32(&loadCount))
+	sc.mu.Lock()
+	assert.Equal([]byte(`{"name":"a"}`), sc.data["a"])
+	sc.mu.Unlock()
+}
@@ -16,104 +16,696 @@ package lruttl
 
 import (
 	"errors"
+	"sync"
 	"time"
 
-	lru "github.com/hashicorp/golang-lru"
+	"github.com/hashicorp/golang-lru/simplelru"
 )
 
 type Key interface{}
 
+// EvictReason is the reason why the item is evicted from cache
+type EvictReason int
+
+const (
+	// EvictReasonCapacity means the item is evicted because the cache is full
+	EvictReasonCapacity EvictReason = iota
+	// EvictReasonExpired means the item is removed because it is expired
+	EvictReasonExpired
+	// EvictReasonRemoved means the item is removed by Remove function
+	EvictReasonRemoved
+	// EvictReasonReplaced means the item is replaced by a new value
+	EvictReasonReplaced
+	// EvictReasonPurged means the item is removed because the cache is purged
+	EvictReasonPurged
+)
+
+// String returns the name of evict reason
+func (r EvictReason) String() string {
+	switch r {
+	case EvictReasonCapacity:
+		return "capacity"
+	case EvictReasonExpired:
+		return "expired"
+	case EvictReasonRemoved:
+		return "removed"
+	case EvictReasonReplaced:
+		return "replaced"
+	case EvictReasonPurged:
+		return "purged"
+	default:
+		return "unknown"
+	}
+}
+
+// TypedCache is a lru cache with ttl, the type of key and value are fixed
+type TypedCache[K comparable, V any] struct {
+	// mu protects the lru and evicted list
+	mu         sync.RWMutex
+	ttl        time.Duration
+	maxEntries int
+	lru        simplelru.LRUCache
+	onEvicted  func(key K, value V)
+	// onEvictedReason is the evicted function with reason
+	onEvictedReason func(key K, value V, reason EvictReason)
+	// evictReason is the reason of current eviction,
+	// it is capacity by default
+	evictReason EvictReason
+	// evicted is the list of evicted items,
+	// the evicted function is called after unlock
+	evicted []evictedItem[K, V]
+	// flight merges the concurrent loads of the same key
+	flight flightGroup[K, V]
+	// staleGrace is the duration that expired item is kept as stale
+	staleGrace time.Duration
+	// staleLoader refreshes the stale item in background
+	staleLoader func(key K) (V, time.Duration, error)
+	// janitor purges the expired items in background
+	janitor *janitor
+	// counter is the statistics of cache
+	counter cacheCounter
+	// maxCost is the max total cost of items, 0 means no limit
+	maxCost int64
+	// cost is the total cost of items
+	cost int64
+	// costFn returns the cost of item
+	costFn func(key K, value V) int64
+	// admission is the tinylfu admission policy, nil means admitting all
+	admission *tinyLFU
+	// marshal is the marshal function of snapshot
+	marshal CacheMarshal
+	// unmarshal is the unmarshal function of snapshot
+	unmarshal CacheUnmarshal
+	// sliding enables the sliding expiration
+	sliding bool
+	// maxLifetime is the max lifetime of item in sliding mode, 0 means no limit
+	maxLifetime time.Duration
+	// jitter randomizes the ttl of Add
+	jitter ttlJitter
+	// closed is true if the cache is closed, the writes will be rejected
+	closed bool
+	// clock is the clock of expiration
+	clock Clock
+}
+
+// Cache is a lru cache with ttl, the key and value can be any type
 type Cache struct {
-	ttl       time.Duration
-	lru       *lru.Cache
+	*TypedCache[Key, interface{}]
+}
+
+// CacheOption cache option, it can be used for both Cache and TypedCache
+type CacheOption = func(opts *cacheOptions)
+
+// TypedCacheOption typed cache option, the cache options can be used as typed cache option,
+// and the type of key and value of typed options is checked at compile time.
+type TypedCacheOption[K comparable, V any] func(opts *cacheOptions)
+
+type cacheOptions struct {
+	// onEvicted is the evicted function
 	onEvicted func(key Key, value interface{})
+	// onEvictedReason is the evicted function with reason
+	onEvictedReason func(key Key, value interface{}, reason EvictReason)
+	// staleGrace is the grace window of stale item
+	staleGrace time.Duration
+	// staleLoader is the loader function of stale item
+	staleLoader func(key Key) (interface{}, time.Duration, error)
+	// janitorInterval is the interval of janitor
+	janitorInterval time.Duration
+	// janitorSampleSize is the max count of items checked by janitor each time
+	janitorSampleSize int
+	// maxCost is the max total cost of items
+	maxCost int64
+	// costFn is the cost function
+	costFn func(value interface{}) int64
+	// tinyLFU enables the tinylfu admission policy
+	tinyLFU bool
+	// tinyLFUSampleSize is the sample size of tinylfu
+	tinyLFUSampleSize int
+	// policy is the replacement policy of cache
+	policy CachePolicy
+	// marshal is the marshal function of snapshot
+	marshal CacheMarshal
+	// unmarshal is the unmarshal function of snapshot
+	unmarshal CacheUnmarshal
+	// sliding enables the sliding expiration
+	sliding bool
+	// maxLifetime is the max lifetime of item in sliding mode
+	maxLifetime time.Duration
+	// jitter is the jitter of ttl
+	jitter ttlJitter
+	// clock is the clock of expiration
+	clock Clock
+	// typed is the functions of typed options, they set the typed functions of typed cache
+	typed []interface{}
 }
 
-// CacheOption cache option
-type CacheOption func(c *Cache)
+type evictedItem[K comparable, V any] struct {
+	key    K
+	value  V
+	reason EvictReason
+}
 
-type cacheItem struct {
+type cacheItem[V any] struct {
 	expiredAt int64
-	value     interface{}
+	// ttl is the original ttl of item
+	ttl int64
+	// deadline is the max expired time of item in sliding mode, 0 means no limit
+	deadline int64
+	cost     int64
+	// refreshed is true if the stale item has been refreshed in background
+	refreshed bool
+	value     V
+}
+
+// slide extends the expired time by the original ttl, but not after the deadline
+func (item *cacheItem[V]) slide(now int64) {
+	if item.ttl <= 0 {
+		return
+	}
+	expiredAt := now + item.ttl
+	if item.deadline > 0 && expiredAt > item.deadline {
+		expiredAt = item.deadline
+	}
+	item.expiredAt = expiredAt
 }
 
-func (item *cacheItem) isExpired() bool {
-	return item.expiredAt < time.Now().UnixNano()
+func (item *cacheItem[V]) isExpired(now int64) bool {
+	return item.expiredAt < now
 }
 
-// New returns a new lru cache with ttl
+func (item *cacheItem[V]) isStale(now int64, grace time.Duration) bool {
+	return item.expiredAt+grace.Nanoseconds() >= now
+}
+
+// New returns a new lru cache with ttl, the typed options can be used only if
+// their key is Key and value is interface{}, otherwise it panics.
 func New(maxEntries int, defaultTTL time.Duration, opts ...CacheOption) *Cache {
+	cacheOpts := cacheOptions{}
+	for _, opt := range opts {
+		opt(&cacheOpts)
+	}
+	return &Cache{
+		TypedCache: newTyped[Key, interface{}](maxEntries, defaultTTL, cacheOpts),
+	}
+}
+
+// NewTyped returns a new typed lru cache with ttl
+func NewTyped[K comparable, V any](maxEntries int, defaultTTL time.Duration, opts ...TypedCacheOption[K, V]) *TypedCache[K, V] {
+	cacheOpts := cacheOptions{}
+	for _, opt := range opts {
+		opt(&cacheOpts)
+	}
+	return newTyped[K, V](maxEntries, defaultTTL, cacheOpts)
+}
+
+func newTyped[K comparable, V any](maxEntries int, defaultTTL time.Duration, cacheOpts cacheOptions) *TypedCache[K, V] {
 	if maxEntries <= 0 || defaultTTL <= 0 {
 		panic(errors.New("maxEntries and default ttl must be gt 0"))
 	}
-	c := &Cache{
-		ttl: defaultTTL,
+	c := &TypedCache[K, V]{
+		ttl:         defaultTTL,
+		maxEntries:  maxEntries,
+		marshal:     cacheOpts.marshal,
+		unmarshal:   cacheOpts.unmarshal,
+		sliding:     cacheOpts.sliding,
+		maxLifetime: cacheOpts.maxLifetime,
+		jitter:      cacheOpts.jitter,
+		clock:       cacheOpts.clock,
 	}
-	for _, opt := range opts {
-		opt(c)
+	if c.clock == nil {
+		c.clock = NewWallClock()
+	}
+	// 通用的函数可用于任意类型
+	if fn := cacheOpts.onEvicted; fn != nil {
+		c.onEvicted = func(key K, value V) {
+			fn(key, value)
+		}
+	}
+	if fn := cacheOpts.onEvictedReason; fn != nil {
+		c.onEvictedReason = func(key K, value V, reason EvictReason) {
+			fn(key, value, reason)
+		}
 	}
-	var fn func(key, value interface{})
-	// 如果有设置on evicted
-	if c.onEvicted != nil {
-		fn = func(key, value interface{}) {
-			c.onEvicted(key, value)
+	if fn := cacheOpts.costFn; fn != nil {
+		c.costFn = func(_ K, value V) int64 {
+			return fn(value)
+		}
+	}
+	if cacheOpts.staleLoader != nil {
+		c.staleGrace = cacheOpts.staleGrace
+		c.staleLoader = typedStaleLoader[K, V](cacheOpts.staleLoader)
+	}
+	// typed option设置的函数优先，类型不匹配只可能是New中使用了其它类型的typed option
+	for _, typed := range cacheOpts.typed {
+		apply, ok := typed.(func(c *TypedCache[K, V]))
+		if !ok {
+			panic(errors.New("the type of typed option does not match the cache"))
+		}
+		apply(c)
+	}
+	if cacheOpts.maxCost > 0 {
+		c.maxCost = cacheOpts.maxCost
+		if c.costFn == nil {
+			c.costFn = func(_ K, value V) int64 {
+				return defaultCost(value)
+			}
+		}
+	} else {
+		// 未设置最大cost则不计算cost
+		c.costFn = nil
+	}
+	if cacheOpts.tinyLFU {
+		sampleSize := cacheOpts.tinyLFUSampleSize
+		if sampleSize <= 0 {
+			sampleSize = 10 * maxEntries
+		}
+		c.admission = newTinyLFU(sampleSize)
+	}
+	hasEvictedFn := c.onEvicted != nil || c.onEvictedReason != nil
+	fn := func(key, value interface{}) {
+		c.counter.evict(c.evictReason)
+		item, _ := value.(*cacheItem[V])
+		if item == nil {
+			return
+		}
+		c.cost -= item.cost
+		// 如果有设置on evicted
+		if !hasEvictedFn {
+			return
 		}
+		k, _ := key.(K)
+		c.addEvicted(k, item.value, c.evictReason)
 	}
 
-	l, err := lru.NewWithEvict(maxEntries, fn)
+	l, err := newPolicy(cacheOpts.policy, maxEntries, fn)
 	// lru 缓存全局初始化，因此直接panic
-	// 除了长度少于0，其它情况不会出错
+	// 除了长度少于0或未知的淘汰策略，其它情况不会出错
 	if err != nil {
 		panic(err)
 	}
 	c.lru = l
+	if cacheOpts.janitorInterval > 0 {
+		c.janitor = newJanitor(cacheOpts.janitorInterval, cacheOpts.janitorSampleSize, c)
+		go c.janitor.run()
+	}
 
 	return c
+}
+
+// unlock releases the lock, then calls the evicted function of evicted items
+func (c *TypedCache[K, V]) unlock() {
+	evicted := c.evicted
+	c.evicted = nil
+	c.mu.Unlock()
+	for _, item := range evicted {
+		// 原有的evicted函数不触发替换
+		if c.onEvicted != nil && item.reason != EvictReasonReplaced {
+			c.onEvicted(item.key, item.value)
+		}
+		if c.onEvictedReason != nil {
+			c.onEvictedReason(item.key, item.value, item.reason)
+		}
+	}
+}
+
+// addEvicted records the evicted item, it should be called with lock
+func (c *TypedCache[K, V]) addEvicted(key K, value V, reason EvictReason) {
+	// 在锁内触发，因此先记录，解锁后再回调
+	c.evicted = append(c.evicted, evictedItem[K, V]{
+		key:    key,
+		value:  value,
+		reason: reason,
+	})
+}
+
+// removeLocked removes the item with reason, it should be called with lock
+func (c *TypedCache[K, V]) removeLocked(key interface{}, reason EvictReason) bool {
+	c.evictReason = reason
+	defer func() {
+		c.evictReason = EvictReasonCapacity
+	}()
+	return c.lru.Remove(key)
+}
 
+// typedStaleLoader converts the stale loader of cache to typed loader,
+// it returns ErrInvalidType if the type of loaded value does not match the cache
+func typedStaleLoader[K comparable, V any](loader func(key Key) (interface{}, time.Duration, error)) func(key K) (V, time.Duration, error) {
+	return func(key K) (V, time.Duration, error) {
+		var value V
+		data, ttl, err := loader(key)
+		if err != nil {
+			return value, ttl, err
+		}
+		// nil无法转换为interface{}，因此直接使用零值
+		if data != nil {
+			v, ok := data.(V)
+			if !ok {
+				return value, ttl, ErrInvalidType
+			}
+			value = v
+		}
+		return value, ttl, nil
+	}
 }
 
 // CacheEvictedOption sets evicted function to cache
 func CacheEvictedOption(fn func(key Key, value interface{})) CacheOption {
-	return func(c *Cache) {
+	return func(opts *cacheOptions) {
+		opts.onEvicted = fn
+	}
+}
+
+// typedOption returns the typed cache option which sets the typed cache by the function
+func typedOption[K comparable, V any](fn func(c *TypedCache[K, V])) TypedCacheOption[K, V] {
+	return func(opts *cacheOptions) {
+		opts.typed = append(opts.typed, fn)
+	}
+}
+
+// TypedCacheEvictedOption sets typed evicted function to typed cache
+func TypedCacheEvictedOption[K comparable, V any](fn func(key K, value V)) TypedCacheOption[K, V] {
+	return typedOption(func(c *TypedCache[K, V]) {
 		c.onEvicted = fn
+	})
+}
+
+// CacheEvictedReasonOption sets evicted function with reason to cache,
+// it is called for capacity eviction, expiration, removal, replacement and purge.
+func CacheEvictedReasonOption(fn func(key Key, value interface{}, reason EvictReason)) CacheOption {
+	return func(opts *cacheOptions) {
+		opts.onEvictedReason = fn
+	}
+}
+
+// TypedCacheEvictedReasonOption sets typed evicted function with reason to typed cache
+func TypedCacheEvictedReasonOption[K comparable, V any](fn func(key K, value V, reason EvictReason)) TypedCacheOption[K, V] {
+	return typedOption(func(c *TypedCache[K, V]) {
+		c.onEvictedReason = fn
+	})
+}
+
+// CacheStaleOption sets the stale-while-revalidate mode to cache.
+// The expired item will be kept for the grace window, Get returns it as stale
+// (value with false) and triggers a single background refresh by the loader.
+// If the refresh fails, it is not retried until the item is removed after the grace window.
+func CacheStaleOption(grace time.Duration, loader func(key Key) (interface{}, time.Duration, error)) CacheOption {
+	return func(opts *cacheOptions) {
+		opts.staleGrace = grace
+		opts.staleLoader = loader
+	}
+}
+
+// TypedCacheStaleOption sets the stale-while-revalidate mode to typed cache
+func TypedCacheStaleOption[K comparable, V any](grace time.Duration, loader func(key K) (V, time.Duration, error)) TypedCacheOption[K, V] {
+	return typedOption(func(c *TypedCache[K, V]) {
+		c.staleGrace = grace
+		c.staleLoader = loader
+	})
+}
+
+// CacheJanitorOption sets a janitor to purge the expired items in background.
+// It checks all items each interval if sampleSize is 0,
+// otherwise it checks at most sampleSize items each interval.
+// The Close function should be called to stop the janitor.
+func CacheJanitorOption(interval time.Duration, sampleSize int) CacheOption {
+	return func(opts *cacheOptions) {
+		opts.janitorInterval = interval
+		opts.janitorSampleSize = sampleSize
+	}
+}
+
+// CacheMaxCostOption sets the max total cost of cache, the oldest items will be evicted
+// when the total cost is over the max cost. The cost of []byte and string is their length
+// and the cost of other types is 1 if the cost function is not set.
+func CacheMaxCostOption(maxCost int64) CacheOption {
+	return func(opts *cacheOptions) {
+		opts.maxCost = maxCost
+	}
+}
+
+// CacheCostOption sets the cost function of value, it is used with max cost option
+func CacheCostOption(fn func(value interface{}) int64) CacheOption {
+	return func(opts *cacheOptions) {
+		opts.costFn = fn
+	}
+}
+
+// TypedCacheCostOption sets the typed cost function of item, it is used with max cost option.
+// The cost of key can be included, e.g. len(key) + len(value).
+func TypedCacheCostOption[K comparable, V any](fn func(key K, value V) int64) TypedCacheOption[K, V] {
+	return typedOption(func(c *TypedCache[K, V]) {
+		c.costFn = fn
+	})
+}
+
+// CacheSlidingOption sets the sliding expiration for cache, each successful Get extends
+// the expired time of item by its original ttl. The item will be expired after
+// the max lifetime even if it is still being used, 0 means no limit.
+func CacheSlidingOption(maxLifetime time.Duration) CacheOption {
+	return func(opts *cacheOptions) {
+		opts.sliding = true
+		opts.maxLifetime = maxLifetime
+	}
+}
+
+// CacheTinyLFUOption sets the tinylfu admission policy for cache,
+// the new key will be rejected if it is less frequent than the eviction victim.
+// The frequency counters are halved after sampleSize accesses,
+// it will be 10 * maxEntries if the sampleSize is 0.
+func CacheTinyLFUOption(sampleSize int) CacheOption {
+	return func(opts *cacheOptions) {
+		opts.tinyLFU = true
+		opts.tinyLFUSampleSize = sampleSize
+	}
+}
+
+// defaultCost returns the length of []byte or string, otherwise returns 1
+func defaultCost(value interface{}) int64 {
+	switch v := value.(type) {
+	case []byte:
+		return int64(len(v))
+	case string:
+		return int64(len(v))
+	default:
+		return 1
 	}
 }
 
 // Add adds a value to the cache, it will use default ttl if the ttl is nil.
-func (c *Cache) Add(key Key, value interface{}, ttl ...time.Duration) {
-	expiredAt := time.Now().UnixNano()
+func (c *TypedCache[K, V]) Add(key K, value V, ttl ...time.Duration) {
+	c.TryAdd(key, value, ttl...)
+}
+
+// TryAdd adds a value to the cache, it will use default ttl if the ttl is nil.
+// It returns false if the value is not admitted by the admission policy,
+// its cost is greater than the max cost of cache, or it is evicted by the
+// replacement policy to keep the total cost under the max cost.
+func (c *TypedCache[K, V]) TryAdd(key K, value V, ttl ...time.Duration) bool {
+	return c.add(key, value, c.itemCost(key, value), ttl...)
+}
+
+// AddWithCost adds a value with cost to the cache, it will use default ttl if the ttl is nil.
+// It returns false if the value is not admitted by the admission policy,
+// its cost is greater than the max cost of cache, or it is evicted by the
+// replacement policy to keep the total cost under the max cost.
+func (c *TypedCache[K, V]) AddWithCost(key K, value V, cost int64, ttl ...time.Duration) bool {
+	return c.add(key, value, cost, ttl...)
+}
+
+func (c *TypedCache[K, V]) add(key K, value V, cost int64, ttl ...time.Duration) bool {
+	c.mu.Lock()
+	defer c.unlock()
+	return c.addLocked(key, c.newItem(value, c.getTTL(ttl...), cost))
+}
+
+// getTTL returns the first ttl, or the default ttl if it is not set
+func (c *TypedCache[K, V]) getTTL(ttl ...time.Duration) time.Duration {
 	if len(ttl) != 0 {
-		expiredAt += ttl[0].Nanoseconds()
-	} else {
-		expiredAt += c.ttl.Nanoseconds()
+		return ttl[0]
+	}
+	return c.ttl
+}
+
+// itemCost returns the cost of item by cost function, it is 0 if the cost function is not set
+func (c *TypedCache[K, V]) itemCost(key K, value V) int64 {
+	if c.costFn == nil {
+		return 0
 	}
-	c.lru.Add(key, &cacheItem{
-		expiredAt: expiredAt,
+	return c.costFn(key, value)
+}
+
+// newItem returns a new cache item which expires after ttl
+func (c *TypedCache[K, V]) newItem(value V, ttl time.Duration, cost int64) *cacheItem[V] {
+	ttl = c.jitter.apply(ttl)
+	now := c.clock.Now()
+	item := &cacheItem[V]{
+		expiredAt: now + ttl.Nanoseconds(),
+		ttl:       ttl.Nanoseconds(),
+		cost:      cost,
 		value:     value,
-	})
+	}
+	if c.sliding && c.maxLifetime > 0 {
+		item.deadline = now + c.maxLifetime.Nanoseconds()
+		if item.expiredAt > item.deadline {
+			item.expiredAt = item.deadline
+		}
+	}
+	return item
+}
+
+// addLocked adds the item to lru, it should be called with lock
+func (c *TypedCache[K, V]) addLocked(key K, item *cacheItem[V]) bool {
+	// 已关闭的缓存不再写入
+	if c.closed {
+		return false
+	}
+	// 超过最大的cost，无法添加，原有的数据也删除
+	if c.maxCost > 0 && item.cost > c.maxCost {
+		c.removeLocked(key, EvictReasonCapacity)
+		return false
+	}
+	data, ok := c.lru.Peek(key)
+	// 新的key需要判断是否准入
+	if !ok && !c.admit(key, item) {
+		c.counter.rejects.Add(1)
+		return false
+	}
+	if ok {
+		c.counter.updates.Add(1)
+		old, _ := data.(*cacheItem[V])
+		if old != nil {
+			// 替换时lru不会触发evicted，因此需要自己处理
+			c.cost -= old.cost
+			if c.onEvictedReason != nil {
+				c.addEvicted(key, old.value, EvictReasonReplaced)
+			}
+		}
+	} else {
+		c.counter.adds.Add(1)
+	}
+	c.lru.Add(key, item)
+	c.cost += item.cost
+	added := true
+	// 超过最大cost则淘汰最旧的数据
+	for c.maxCost > 0 && c.cost > c.maxCost {
+		evictedKey, _, ok := c.lru.RemoveOldest()
+		if !ok {
+			break
+		}
+		// 2Q与ARC有可能淘汰刚添加的数据
+		if evictedKey == interface{}(key) {
+			added = false
+		}
+	}
+	return added
+}
+
+// admit returns true if the new key can be added to cache, it should be called with lock
+func (c *TypedCache[K, V]) admit(key K, item *cacheItem[V]) bool {
+	if c.admission == nil {
+		return true
+	}
+	// 未满时无需淘汰，直接添加
+	if c.lru.Len() < c.maxEntries &&
+		(c.maxCost <= 0 || c.cost+item.cost <= c.maxCost) {
+		return true
+	}
+	victim, _, ok := c.lru.GetOldest()
+	if !ok {
+		return true
+	}
+	return c.admission.admit(keyHash(key), keyHash(victim))
 }
 
 // Get returns value and exists from the cache by key, if value is expired then remove it.
 // If the value is expired, value is not nil but exists is false.
-func (c *Cache) Get(key Key) (interface{}, bool) {
+// In stale mode, the expired value in grace window is not removed and will be refreshed in background.
+func (c *TypedCache[K, V]) Get(key K) (V, bool) {
+	value, ok, _ := c.get(key)
+	return value, ok
+}
+
+func (c *TypedCache[K, V]) get(key K) (value V, ok bool, stale bool) {
+	c.mu.Lock()
+	defer c.unlock()
+	return c.getLocked(key)
+}
+
+// getLocked gets the value of key, it should be called with lock
+func (c *TypedCache[K, V]) getLocked(key K) (value V, ok bool, stale bool) {
+	if c.admission != nil {
+		c.admission.increment(keyHash(key))
+	}
 	data, ok := c.lru.Get(key)
 	if !ok {
-		return nil, false
+		c.counter.misses.Add(1)
+		return
 	}
-	item, ok := data.(*cacheItem)
+	item, ok := data.(*cacheItem[V])
 	if !ok {
-		return nil, false
+		c.counter.misses.Add(1)
+		return
 	}
 	// 过期的元素数据也返回，但ok为false
-	// 由于未做并发控制，因此有可能并发时导致数据被清除（另外一个goroutine刚好在更新)
-	// 由于是缓存数据并不会导致数据出错，因此不添加并发控制
-	value := item.value
-	if item.isExpired() {
+	value = item.value
+	now := c.clock.Now()
+	if item.isExpired(now) {
+		c.counter.expiredHits.Add(1)
+		// 在宽限期内的数据保留，并在后台刷新
+		if c.staleLoader != nil && item.isStale(now, c.staleGrace) {
+			// 每个过期的数据只刷新一次，避免加载失败时每次获取都重新加载
+			if !item.refreshed {
+				item.refreshed = true
+				c.flight.goDo(key, func() (V, error) {
+					return c.load(key, c.staleLoader)
+				})
+			}
+			return value, false, true
+		}
 		// 过期的元素删除
-		c.lru.Remove(key)
-		return value, false
+		c.removeLocked(key, EvictReasonExpired)
+		return value, false, false
 	}
-	return value, true
+	c.counter.hits.Add(1)
+	// 滑动过期，每次获取成功则延长过期时间
+	if c.sliding {
+		item.slide(now)
+	}
+	return value, true, false
+}
+
+// GetOrLoad returns the value from the cache, if it does not exist or is expired,
+// the loader will be called and its value will be added to the cache with the returned ttl
+// (default ttl if the returned ttl is 0). The concurrent loads of the same key are merged
+// into one, and the error of loader is returned to all callers without caching.
+// In stale mode, the stale value is returned directly without waiting for the refresh.
+func (c *TypedCache[K, V]) GetOrLoad(key K, loader func(key K) (V, time.Duration, error)) (V, error) {
+	value, ok, stale := c.get(key)
+	if ok || stale {
+		return value, nil
+	}
+	return c.flight.do(key, func() (V, error) {
+		return c.load(key, loader)
+	})
+}
+
+// load calls the loader and adds the value to the cache
+func (c *TypedCache[K, V]) load(key K, loader func(key K) (V, time.Duration, error)) (V, error) {
+	// 有可能在等待期间其它goroutine已加载完成
+	value, ok := c.Peek(key)
+	if ok {
+		return value, nil
+	}
+	value, ttl, err := loader(key)
+	if err != nil {
+		return value, err
+	}
+	if ttl > 0 {
+		c.Add(key, value, ttl)
+	} else {
+		c.Add(key, value)
+	}
+	return value, nil
 }
 
 // GetBytes is the same as Get function, but returns []byte
@@ -126,21 +718,24 @@ func (c *Cache) GetBytes(key Key) ([]byte, bool) {
 	return buf, ok
 }
 
-// TTL returns the ttl of key
-func (c *Cache) TTL(key Key) time.Duration {
+// TTL returns the ttl of key, it returns -2 if the key does not exist,
+// and returns -1 if the key is expired or has no expiration.
+func (c *TypedCache[K, V]) TTL(key K) time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	data, ok := c.lru.Peek(key)
 	if !ok {
 		// 元素不存在
 		return time.Duration(-2)
 	}
-	item, ok := data.(*cacheItem)
+	item, ok := data.(*cacheItem[V])
 	if !ok {
 		// 元素转换失败则认为不存在
 		return time.Duration(-2)
 	}
-	now := time.Now().UnixNano()
-	if item.expiredAt <= now {
-		// 元素已过期
+	now := c.clock.Now()
+	// 元素已过期或永不过期
+	if item.expiredAt <= now || item.expiredAt == noExpiration {
 		return time.Duration(-1)
 	}
 	return time.Duration(item.expiredAt - now)
@@ -149,18 +744,21 @@ func (c *Cache) TTL(key Key) time.Duration {
 // Peek get a key's value from the cache, but not move to front.
 // The performance is better than get.
 // It will not be removed if the cache is expired.
-func (c *Cache) Peek(key Key) (interface{}, bool) {
+func (c *TypedCache[K, V]) Peek(key K) (V, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var value V
 	data, ok := c.lru.Peek(key)
 	if !ok {
-		return nil, false
+		return value, false
 	}
-	item, ok := data.(*cacheItem)
+	item, ok := data.(*cacheItem[V])
 	if !ok {
-		return nil, false
+		return value, false
 	}
 	// 过期的元素数据也返回，但ok为false
-	value := item.value
-	if item.isExpired() {
+	value = item.value
+	if item.isExpired(c.clock.Now()) {
 		// 过期不清除
 		return value, false
 	}
@@ -178,21 +776,102 @@ func (c *Cache) PeekBytes(key Key) ([]byte, bool) {
 }
 
 // Remove removes the key's value from the cache.
-func (c *Cache) Remove(key Key) {
-	c.lru.Remove(key)
+func (c *TypedCache[K, V]) Remove(key K) {
+	c.mu.Lock()
+	defer c.unlock()
+	c.removeLocked(key, EvictReasonRemoved)
+}
+
+// Cost returns the total cost of items in the cache.
+func (c *TypedCache[K, V]) Cost() int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cost
 }
 
 // Len returns the number of items in the cache.
-func (c *Cache) Len() int {
+func (c *TypedCache[K, V]) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.lru.Len()
 }
 
-// Keys gets all keys of cache
-func (c *Cache) Keys() []Key {
+// Keys gets all keys of cache, from oldest to newest for the lru policy.
+// For the 2Q and ARC policy, the keys of recent list are followed by the keys of frequent list.
+func (c *TypedCache[K, V]) Keys() []K {
+	c.mu.RLock()
 	keys := c.lru.Keys()
-	result := make([]Key, len(keys))
+	c.mu.RUnlock()
+	result := make([]K, len(keys))
 	for i, k := range keys {
-		result[i] = k
+		result[i], _ = k.(K)
 	}
 	return result
 }
+
+// Close stops the background janitor of the cache, and the further writes will be rejected.
+// The cache can still be read and removed after closed.
+func (c *TypedCache[K, V]) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+	if c.janitor != nil {
+		c.janitor.stop()
+	}
+	return nil
+}
+
+// Purge removes all items from the cache, the evicted function is called with purged reason.
+func (c *TypedCache[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.unlock()
+	c.evictReason = EvictReasonPurged
+	defer func() {
+		c.evictReason = EvictReasonCapacity
+	}()
+	c.lru.Purge()
+}
+
+// Resize changes the max entries of cache, it returns the count of evicted items.
+// It does nothing if the size is not greater than 0.
+func (c *TypedCache[K, V]) Resize(size int) (evicted int) {
+	if size <= 0 {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.unlock()
+	c.maxEntries = size
+	return c.lru.Resize(size)
+}
+
+// purgeExpired removes the expired items of keys, the stale items in grace window are kept.
+// It returns the count of removed items.
+func (c *TypedCache[K, V]) purgeExpired(keys []interface{}) int {
+	c.mu.Lock()
+	defer c.unlock()
+	count := 0
+	now := c.clock.Now()
+	for _, key := range keys {
+		data, ok := c.lru.Peek(key)
+		if !ok {
+			continue
+		}
+		item, _ := data.(*cacheItem[V])
+		if item == nil || !item.isExpired(now) {
+			continue
+		}
+		if c.staleLoader != nil && item.isStale(now, c.staleGrace) {
+			continue
+		}
+		c.removeLocked(key, EvictReasonExpired)
+		count++
+	}
+	return count
+}
+
+// lruKeys returns the keys of lru in the order of Keys
+func (c *TypedCache[K, V]) lruKeys() []interface{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.lru.Keys()
+}
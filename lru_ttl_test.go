@@ -15,7 +15,10 @@
 package lruttl
 
 import (
+	"bytes"
+	"errors"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -225,3 +228,405 @@ func TestGetAndPeekBytes(t *testing.T) {
 	assert.True(ok)
 	assert.Equal([]byte("abc"), data)
 }
+
+func TestTypedCache(t *testing.T) {
+	assert := assert.New(t)
+
+	evictedKeys := make([]string, 0)
+	cache := NewTyped[string, int](2, time.Minute, TypedCacheEvictedOption(func(key string, value int) {
+		evictedKeys = append(evictedKeys, key)
+	}))
+	cache.Add("a", 1)
+	cache.Add("b", 2, 100*time.Millisecond)
+
+	value, ok := cache.Get("a")
+	assert.True(ok)
+	assert.Equal(1, value)
+
+	value, ok = cache.Peek("b")
+	assert.True(ok)
+	assert.Equal(2, value)
+	assert.Equal([]string{"b", "a"}, cache.Keys())
+
+	// 不存在的key返回零值
+	value, ok = cache.Get("c")
+	assert.False(ok)
+	assert.Equal(0, value)
+
+	time.Sleep(200 * time.Millisecond)
+	// 过期的数据也返回，但ok为false
+	value, ok = cache.Get("b")
+	assert.False(ok)
+	assert.Equal(2, value)
+	assert.Equal(1, cache.Len())
+	assert.Equal([]string{"b"}, evictedKeys)
+}
+
+func TestGetOrLoad(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := New(10, time.Minute)
+	var count int32
+	loader := func(key Key) (interface{}, time.Duration, error) {
+		atomic.AddInt32(&count, 1)
+		time.Sleep(50 * time.Millisecond)
+		return "value", 100 * time.Millisecond, nil
+	}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := cache.GetOrLoad("key", loader)
+			assert.Nil(err)
+			assert.Equal("value", value)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(int32(1), atomic.LoadInt32(&count))
+	ttl := cache.TTL("key")
+	assert.True(ttl > 0 && ttl <= 100*time.Millisecond)
+
+	// 已缓存，不再调用loader
+	value, err := cache.GetOrLoad("key", loader)
+	assert.Nil(err)
+	assert.Equal("value", value)
+	assert.Equal(int32(1), atomic.LoadInt32(&count))
+
+	// 出错不缓存
+	customErr := errors.New("custom error")
+	_, err = cache.GetOrLoad("error", func(key Key) (interface{}, time.Duration, error) {
+		return nil, 0, customErr
+	})
+	assert.Equal(customErr, err)
+	_, ok := cache.Peek("error")
+	assert.False(ok)
+	assert.Equal(time.Duration(-2), cache.TTL("error"))
+}
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	assert := assert.New(t)
+
+	var count int32
+	cache := NewTyped[string, int](10, time.Minute, TypedCacheStaleOption(200*time.Millisecond, func(key string) (int, time.Duration, error) {
+		time.Sleep(20 * time.Millisecond)
+		return int(atomic.AddInt32(&count, 1)) + 1, time.Minute, nil
+	}))
+	cache.Add("a", 1, 50*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+
+	// 过期但在宽限期内，返回旧数据并只触发一次刷新
+	for i := 0; i < 5; i++ {
+		value, ok := cache.Get("a")
+		assert.False(ok)
+		assert.Equal(1, value)
+	}
+	value, err := cache.GetOrLoad("a", func(key string) (int, time.Duration, error) {
+		return 0, 0, errors.New("should not be called")
+	})
+	assert.Nil(err)
+	assert.Equal(1, value)
+
+	time.Sleep(50 * time.Millisecond)
+	value, ok := cache.Get("a")
+	assert.True(ok)
+	assert.Equal(2, value)
+	assert.Equal(int32(1), atomic.LoadInt32(&count))
+
+	// 超过宽限期则删除
+	cache.Add("b", 1, 10*time.Millisecond)
+	time.Sleep(250 * time.Millisecond)
+	_, ok = cache.Get("b")
+	assert.False(ok)
+	_, ok = cache.Peek("b")
+	assert.False(ok)
+	assert.Equal(time.Duration(-2), cache.TTL("b"))
+}
+
+func TestStaleRefreshFailed(t *testing.T) {
+	assert := assert.New(t)
+
+	var count int32
+	clock := NewFakeClock(time.Now())
+	cache := NewTyped(10, time.Minute,
+		CacheClockOption(clock),
+		TypedCacheStaleOption(time.Minute, func(key string) (int, time.Duration, error) {
+			atomic.AddInt32(&count, 1)
+			return 0, 0, errors.New("backend is down")
+		}),
+	)
+	cache.Add("a", 1, time.Second)
+	clock.Advance(2 * time.Second)
+
+	// 刷新失败不再重试，只调用一次loader
+	for i := 0; i < 50; i++ {
+		value, ok := cache.Get("a")
+		assert.False(ok)
+		assert.Equal(1, value)
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(int32(1), atomic.LoadInt32(&count))
+
+	// 重新添加的数据过期后可再次刷新
+	cache.Add("a", 2, time.Second)
+	clock.Advance(2 * time.Second)
+	value, ok := cache.Get("a")
+	assert.False(ok)
+	assert.Equal(2, value)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(int32(2), atomic.LoadInt32(&count))
+
+	// 超过宽限期则删除
+	clock.Advance(2 * time.Minute)
+	_, ok = cache.Get("a")
+	assert.False(ok)
+	assert.Equal(time.Duration(-2), cache.TTL("a"))
+}
+
+func TestCacheEvictedReason(t *testing.T) {
+	assert := assert.New(t)
+
+	reasons := make(map[string]EvictReason)
+	evictedCount := 0
+	cache := New(2, time.Minute, CacheEvictedReasonOption(func(key Key, value interface{}, reason EvictReason) {
+		reasons[key.(string)] = reason
+	}), CacheEvictedOption(func(key Key, value interface{}) {
+		evictedCount++
+	}))
+
+	cache.Add("a", 1)
+	cache.Add("a", 2)
+	assert.Equal(EvictReasonReplaced, reasons["a"])
+	// 替换不触发原有的evicted函数
+	assert.Equal(0, evictedCount)
+
+	cache.Add("b", 1)
+	cache.Add("c", 1)
+	assert.Equal(EvictReasonCapacity, reasons["a"])
+
+	cache.Remove("b")
+	assert.Equal(EvictReasonRemoved, reasons["b"])
+
+	cache.Add("d", 1, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	cache.Get("d")
+	assert.Equal(EvictReasonExpired, reasons["d"])
+	assert.Equal(3, evictedCount)
+
+	assert.Equal("capacity", EvictReasonCapacity.String())
+	assert.Equal("expired", EvictReasonExpired.String())
+	assert.Equal("removed", EvictReasonRemoved.String())
+	assert.Equal("replaced", EvictReasonReplaced.String())
+	assert.Equal("purged", EvictReasonPurged.String())
+	assert.Equal("unknown", EvictReason(-1).String())
+}
+
+func TestCacheMaxCost(t *testing.T) {
+	assert := assert.New(t)
+
+	reasons := make(map[Key]EvictReason)
+	cache := New(100, time.Minute, CacheMaxCostOption(10), CacheEvictedReasonOption(func(key Key, value interface{}, reason EvictReason) {
+		reasons[key] = reason
+	}))
+	cache.Add("a", []byte("abcd"))
+	cache.Add("b", "abcd")
+	assert.Equal(int64(8), cache.Cost())
+	// 其它类型的cost为1
+	cache.Add("c", 1)
+	assert.Equal(int64(9), cache.Cost())
+
+	// 超过最大cost，淘汰最旧的数据
+	cache.Add("d", "abc")
+	assert.Equal(int64(8), cache.Cost())
+	assert.Equal([]Key{"b", "c", "d"}, cache.Keys())
+	assert.Equal(EvictReasonCapacity, reasons["a"])
+
+	// 替换时更新cost
+	cache.Add("b", "a")
+	assert.Equal(int64(5), cache.Cost())
+
+	// 大于最大cost的无法添加，且原有数据被删除
+	assert.False(cache.AddWithCost("b", "a", 11))
+	_, ok := cache.Get("b")
+	assert.False(ok)
+	assert.Equal(int64(4), cache.Cost())
+
+	assert.True(cache.AddWithCost("e", "a", 6))
+	assert.Equal(int64(10), cache.Cost())
+	cache.Remove("e")
+	assert.Equal(int64(4), cache.Cost())
+}
+
+func TestTypedCacheCost(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := NewTyped(10, time.Minute, CacheMaxCostOption(5), TypedCacheCostOption(func(key string, value []int) int64 {
+		return int64(len(value))
+	}))
+	cache.Add("a", []int{1, 2, 3})
+	cache.Add("b", []int{1, 2, 3})
+	assert.Equal(int64(3), cache.Cost())
+	assert.Equal([]string{"b"}, cache.Keys())
+}
+
+func TestSlidingExpiration(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := New(10, time.Minute, CacheSlidingOption(0))
+	cache.Add("a", 1, 50*time.Millisecond)
+	for i := 0; i < 5; i++ {
+		time.Sleep(30 * time.Millisecond)
+		// 每次获取成功都延长过期时间
+		_, ok := cache.Get("a")
+		assert.True(ok)
+	}
+	// peek不会延长过期时间
+	time.Sleep(30 * time.Millisecond)
+	_, ok := cache.Peek("a")
+	assert.True(ok)
+	time.Sleep(30 * time.Millisecond)
+	_, ok = cache.Get("a")
+	assert.False(ok)
+}
+
+func TestSlidingExpirationMaxLifetime(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := New(10, time.Minute, CacheSlidingOption(100*time.Millisecond))
+	// ttl大于最大生命周期
+	cache.Add("b", 1, time.Second)
+	assert.True(cache.TTL("b") <= 100*time.Millisecond)
+
+	cache.Add("a", 1, 50*time.Millisecond)
+	for i := 0; i < 3; i++ {
+		time.Sleep(30 * time.Millisecond)
+		_, ok := cache.Get("a")
+		assert.True(ok)
+	}
+	// 超过最大生命周期后过期
+	time.Sleep(30 * time.Millisecond)
+	_, ok := cache.Get("a")
+	assert.False(ok)
+}
+
+func TestPurgeAndResize(t *testing.T) {
+	assert := assert.New(t)
+
+	reasons := make([]EvictReason, 0)
+	cache := New(10, time.Minute, CacheMaxCostOption(100), CacheEvictedReasonOption(func(key Key, value interface{}, reason EvictReason) {
+		reasons = append(reasons, reason)
+	}))
+	for i := 0; i < 5; i++ {
+		cache.Add(i, "ab")
+	}
+	assert.Equal(int64(10), cache.Cost())
+
+	// 缩小容量淘汰最旧的数据
+	assert.Equal(2, cache.Resize(3))
+	assert.Equal([]Key{2, 3, 4}, cache.Keys())
+	assert.Equal(0, cache.Resize(0))
+	cache.Add(5, "ab")
+	assert.Equal(3, cache.Len())
+	assert.Equal(0, cache.Resize(20))
+
+	cache.Purge()
+	assert.Equal(0, cache.Len())
+	assert.Equal(int64(0), cache.Cost())
+	assert.Equal([]EvictReason{
+		EvictReasonCapacity,
+		EvictReasonCapacity,
+		EvictReasonCapacity,
+		EvictReasonPurged,
+		EvictReasonPurged,
+		EvictReasonPurged,
+	}, reasons)
+	assert.Equal(uint64(3), cache.Stats().Evictions[EvictReasonPurged])
+}
+
+func TestClose(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := New(10, time.Minute, CacheJanitorOption(time.Millisecond, 0))
+	cache.Add("a", int64(1))
+	assert.Nil(cache.Close())
+	// 重复关闭
+	assert.Nil(cache.Close())
+
+	// 关闭后无法写入
+	cache.Add("b", 1)
+	assert.False(cache.TryAdd("b", 1))
+	assert.False(cache.AddIfAbsent("b", 1))
+	assert.False(cache.Replace("a", 2))
+	assert.False(cache.Expire("a", time.Second))
+	assert.False(cache.Persist("a"))
+	_, err := cache.IncrBy("a", 1)
+	assert.Equal(ErrCacheClosed, err)
+	assert.Equal(ErrCacheClosed, cache.Load(&bytes.Buffer{}))
+	_, ok := cache.Peek("b")
+	assert.False(ok)
+
+	// 可以读取与删除
+	value, ok := cache.Get("a")
+	assert.True(ok)
+	assert.Equal(int64(1), value)
+	cache.Remove("a")
+	assert.Equal(0, cache.Len())
+}
+
+func TestTypedCacheOption(t *testing.T) {
+	assert := assert.New(t)
+
+	commonKeys := make([]Key, 0)
+	typedKeys := make([]string, 0)
+	cache := NewTyped(1, time.Minute,
+		CacheEvictedOption(func(key Key, value interface{}) {
+			commonKeys = append(commonKeys, key)
+		}),
+		CacheEvictedReasonOption(func(key Key, value interface{}, reason EvictReason) {
+			commonKeys = append(commonKeys, key)
+		}),
+		// typed option设置的函数优先
+		TypedCacheEvictedOption(func(key string, value int) {
+			typedKeys = append(typedKeys, key)
+		}),
+	)
+	cache.Add("a", 1)
+	cache.Add("b", 2)
+	assert.Equal([]string{"a"}, typedKeys)
+	assert.Equal([]Key{"a"}, commonKeys)
+
+	// 通用的stale loader返回的类型不匹配
+	staleCache := NewTyped[string, int](10, time.Minute,
+		CacheStaleOption(time.Minute, func(key Key) (interface{}, time.Duration, error) {
+			return "1", 0, nil
+		}),
+	)
+	_, _, err := staleCache.staleLoader("a")
+	assert.Equal(ErrInvalidType, err)
+
+	// 未设置最大cost则不计算cost
+	costCache := NewTyped(10, time.Minute, TypedCacheCostOption(func(key string, value int) int64 {
+		return int64(value)
+	}))
+	costCache.Add("a", 10)
+	assert.Equal(int64(0), costCache.Cost())
+
+	// cost包括key的长度
+	costCache = NewTyped(10, time.Minute, CacheMaxCostOption(100), TypedCacheCostOption(func(key string, value int) int64 {
+		return int64(len(key) + value)
+	}))
+	costCache.Add("abc", 10)
+	assert.Equal(int64(13), costCache.Cost())
+
+	// Cache可使用key与value为interface{}的typed option，其它类型则panic
+	var evictedKey Key
+	anyCache := New(1, time.Minute, TypedCacheEvictedOption(func(key Key, value interface{}) {
+		evictedKey = key
+	}))
+	anyCache.Add("a", 1)
+	anyCache.Add("b", 2)
+	assert.Equal("a", evictedKey)
+	assert.Panics(func() {
+		New(1, time.Minute, TypedCacheEvictedOption(func(key string, value int) {}))
+	})
+}
@@ -0,0 +1,475 @@
+// Copyright 2022 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The replacement policies of cache, all of them implement simplelru.LRUCache
+// and call the evicted function when the item is evicted or removed.
+// They are not thread safe, and should be used with lock.
+
+package lruttl
+
+import (
+	"errors"
+
+	"github.com/hashicorp/golang-lru/simplelru"
+)
+
+// CachePolicy is the replacement policy of cache
+type CachePolicy int
+
+const (
+	// CachePolicyLRU evicts the least recently used item
+	CachePolicyLRU CachePolicy = iota
+	// CachePolicy2Q tracks the recently and frequently used items separately
+	CachePolicy2Q
+	// CachePolicyARC adapts the size of recent and frequent list automatically
+	CachePolicyARC
+)
+
+const (
+	// twoQueueRecentRatio is the ratio of recent list in 2Q
+	twoQueueRecentRatio = 0.25
+	// twoQueueGhostRatio is the ratio of ghost entries in 2Q
+	twoQueueGhostRatio = 0.5
+)
+
+// CachePolicyOption sets the replacement policy of cache, it is lru by default
+func CachePolicyOption(policy CachePolicy) CacheOption {
+	return func(opts *cacheOptions) {
+		opts.policy = policy
+	}
+}
+
+// newPolicy returns a simplelru.LRUCache of the policy
+func newPolicy(policy CachePolicy, size int, onEvict simplelru.EvictCallback) (simplelru.LRUCache, error) {
+	if size <= 0 {
+		return nil, errors.New("must provide a positive size")
+	}
+	switch policy {
+	case CachePolicyLRU:
+		return simplelru.NewLRU(size, onEvict)
+	case CachePolicy2Q:
+		return newTwoQueue(size, onEvict), nil
+	case CachePolicyARC:
+		return newARC(size, onEvict), nil
+	default:
+		return nil, errors.New("unknown cache policy")
+	}
+}
+
+// mustNewLRU returns a simplelru.LRU without evicted function,
+// the size is at least 1
+func mustNewLRU(size int) *simplelru.LRU {
+	if size < 1 {
+		size = 1
+	}
+	l, _ := simplelru.NewLRU(size, nil)
+	return l
+}
+
+// twoQueue is the 2Q replacement policy, the new item is added to the recent list,
+// and it will be moved to the frequent list when it is accessed again.
+// The keys evicted from the recent list are kept in the ghost list,
+// they will be added to the frequent list directly if they are added again.
+type twoQueue struct {
+	size       int
+	recentSize int
+	recent     *simplelru.LRU
+	frequent   *simplelru.LRU
+	ghost      *simplelru.LRU
+	onEvict    simplelru.EvictCallback
+}
+
+func newTwoQueue(size int, onEvict simplelru.EvictCallback) *twoQueue {
+	q := &twoQueue{
+		onEvict: onEvict,
+	}
+	q.setSize(size)
+	q.recent = mustNewLRU(size)
+	q.frequent = mustNewLRU(size)
+	q.ghost = mustNewLRU(int(float64(size) * twoQueueGhostRatio))
+	return q
+}
+
+func (q *twoQueue) setSize(size int) {
+	q.size = size
+	q.recentSize = int(float64(size) * twoQueueRecentRatio)
+}
+
+func (q *twoQueue) evict(key, value interface{}) {
+	if q.onEvict != nil {
+		q.onEvict(key, value)
+	}
+}
+
+func (q *twoQueue) Add(key, value interface{}) bool {
+	// 已在frequent中，则更新
+	if q.frequent.Contains(key) {
+		q.frequent.Add(key, value)
+		return false
+	}
+	// 在recent中，再次添加则移至frequent
+	if q.recent.Contains(key) {
+		q.recent.Remove(key)
+		q.frequent.Add(key, value)
+		return false
+	}
+	// 最近被淘汰的key，直接添加至frequent
+	if q.ghost.Contains(key) {
+		evicted := q.ensureSpace(true)
+		q.ghost.Remove(key)
+		q.frequent.Add(key, value)
+		return evicted
+	}
+	evicted := q.ensureSpace(false)
+	q.recent.Add(key, value)
+	return evicted
+}
+
+// victimInRecent returns true if the victim is in the recent list
+func (q *twoQueue) victimInRecent(recentEvict bool) bool {
+	recentLen := q.recent.Len()
+	if recentLen == 0 {
+		return false
+	}
+	if q.frequent.Len() == 0 {
+		return true
+	}
+	return recentLen > q.recentSize || (recentLen == q.recentSize && !recentEvict)
+}
+
+// removeVictim removes the victim item and calls the evicted function
+func (q *twoQueue) removeVictim(recentEvict bool) (interface{}, interface{}, bool) {
+	if q.victimInRecent(recentEvict) {
+		key, value, ok := q.recent.RemoveOldest()
+		if ok {
+			q.ghost.Add(key, nil)
+			q.evict(key, value)
+		}
+		return key, value, ok
+	}
+	key, value, ok := q.frequent.RemoveOldest()
+	if ok {
+		q.evict(key, value)
+	}
+	return key, value, ok
+}
+
+// ensureSpace evicts the victim if the cache is full
+func (q *twoQueue) ensureSpace(recentEvict bool) bool {
+	if q.Len() < q.size {
+		return false
+	}
+	_, _, ok := q.removeVictim(recentEvict)
+	return ok
+}
+
+func (q *twoQueue) Get(key interface{}) (interface{}, bool) {
+	if value, ok := q.frequent.Get(key); ok {
+		return value, true
+	}
+	// 再次访问则移至frequent
+	if value, ok := q.recent.Peek(key); ok {
+		q.recent.Remove(key)
+		q.frequent.Add(key, value)
+		return value, true
+	}
+	return nil, false
+}
+
+func (q *twoQueue) Contains(key interface{}) bool {
+	return q.frequent.Contains(key) || q.recent.Contains(key)
+}
+
+func (q *twoQueue) Peek(key interface{}) (interface{}, bool) {
+	if value, ok := q.frequent.Peek(key); ok {
+		return value, true
+	}
+	return q.recent.Peek(key)
+}
+
+func (q *twoQueue) Remove(key interface{}) bool {
+	q.ghost.Remove(key)
+	for _, l := range []*simplelru.LRU{q.frequent, q.recent} {
+		if value, ok := l.Peek(key); ok {
+			l.Remove(key)
+			q.evict(key, value)
+			return true
+		}
+	}
+	return false
+}
+
+func (q *twoQueue) RemoveOldest() (interface{}, interface{}, bool) {
+	return q.removeVictim(false)
+}
+
+func (q *twoQueue) GetOldest() (interface{}, interface{}, bool) {
+	if q.victimInRecent(false) {
+		return q.recent.GetOldest()
+	}
+	return q.frequent.GetOldest()
+}
+
+// Keys returns the keys of recent list followed by the keys of frequent list,
+// each list is from oldest to newest, so it is not the recency order of all keys.
+func (q *twoQueue) Keys() []interface{} {
+	return append(q.recent.Keys(), q.frequent.Keys()...)
+}
+
+func (q *twoQueue) Len() int {
+	return q.recent.Len() + q.frequent.Len()
+}
+
+func (q *twoQueue) Purge() {
+	for _, l := range []*simplelru.LRU{q.recent, q.frequent} {
+		for _, key := range l.Keys() {
+			value, _ := l.Peek(key)
+			l.Remove(key)
+			q.evict(key, value)
+		}
+	}
+	q.ghost.Purge()
+}
+
+func (q *twoQueue) Resize(size int) int {
+	q.setSize(size)
+	evicted := 0
+	for q.Len() > size {
+		if _, _, ok := q.removeVictim(false); !ok {
+			break
+		}
+		evicted++
+	}
+	q.recent.Resize(size)
+	q.frequent.Resize(size)
+	q.ghost.Resize(maxInt(1, int(float64(size)*twoQueueGhostRatio)))
+	return evicted
+}
+
+// arc is the adaptive replacement cache policy, it keeps the recent list(t1) and
+// frequent list(t2), and their ghost lists(b1, b2). The target size of t1 is
+// adapted by the hits of ghost lists.
+type arc struct {
+	size    int
+	p       int
+	t1      *simplelru.LRU
+	b1      *simplelru.LRU
+	t2      *simplelru.LRU
+	b2      *simplelru.LRU
+	onEvict simplelru.EvictCallback
+}
+
+func newARC(size int, onEvict simplelru.EvictCallback) *arc {
+	return &arc{
+		size:    size,
+		t1:      mustNewLRU(size),
+		b1:      mustNewLRU(size),
+		t2:      mustNewLRU(size),
+		b2:      mustNewLRU(size),
+		onEvict: onEvict,
+	}
+}
+
+func (a *arc) evict(key, value interface{}) {
+	if a.onEvict != nil {
+		a.onEvict(key, value)
+	}
+}
+
+func (a *arc) Add(key, value interface{}) bool {
+	// 在t1中，再次添加则移至t2
+	if a.t1.Contains(key) {
+		a.t1.Remove(key)
+		a.t2.Add(key, value)
+		return false
+	}
+	if a.t2.Contains(key) {
+		a.t2.Add(key, value)
+		return false
+	}
+	evicted := false
+	// 命中b1，增大t1的目标大小
+	if a.b1.Contains(key) {
+		delta := 1
+		if a.b1.Len() < a.b2.Len() {
+			delta = a.b2.Len() / a.b1.Len()
+		}
+		a.p = minInt(a.p+delta, a.size)
+		if a.Len() >= a.size {
+			evicted = a.replace(false)
+		}
+		a.b1.Remove(key)
+		a.t2.Add(key, value)
+		return evicted
+	}
+	// 命中b2，减小t1的目标大小
+	if a.b2.Contains(key) {
+		delta := 1
+		if a.b2.Len() < a.b1.Len() {
+			delta = a.b1.Len() / a.b2.Len()
+		}
+		a.p = maxInt(a.p-delta, 0)
+		if a.Len() >= a.size {
+			evicted = a.replace(true)
+		}
+		a.b2.Remove(key)
+		a.t2.Add(key, value)
+		return evicted
+	}
+	if a.Len() >= a.size {
+		evicted = a.replace(false)
+	}
+	// 控制ghost列表的大小
+	if a.b1.Len() > a.size-a.p {
+		a.b1.RemoveOldest()
+	}
+	if a.b2.Len() > a.p {
+		a.b2.RemoveOldest()
+	}
+	a.t1.Add(key, value)
+	return evicted
+}
+
+// victimInT1 returns true if the victim is in t1
+func (a *arc) victimInT1(b2ContainsKey bool) bool {
+	t1Len := a.t1.Len()
+	if t1Len == 0 {
+		return false
+	}
+	if a.t2.Len() == 0 {
+		return true
+	}
+	return t1Len > a.p || (t1Len == a.p && b2ContainsKey)
+}
+
+// removeVictim removes the victim item to ghost list and calls the evicted function
+func (a *arc) removeVictim(b2ContainsKey bool) (interface{}, interface{}, bool) {
+	if a.victimInT1(b2ContainsKey) {
+		key, value, ok := a.t1.RemoveOldest()
+		if ok {
+			a.b1.Add(key, nil)
+			a.evict(key, value)
+		}
+		return key, value, ok
+	}
+	key, value, ok := a.t2.RemoveOldest()
+	if ok {
+		a.b2.Add(key, nil)
+		a.evict(key, value)
+	}
+	return key, value, ok
+}
+
+func (a *arc) replace(b2ContainsKey bool) bool {
+	_, _, ok := a.removeVictim(b2ContainsKey)
+	return ok
+}
+
+func (a *arc) Get(key interface{}) (interface{}, bool) {
+	// 再次访问则移至t2
+	if value, ok := a.t1.Peek(key); ok {
+		a.t1.Remove(key)
+		a.t2.Add(key, value)
+		return value, true
+	}
+	return a.t2.Get(key)
+}
+
+func (a *arc) Contains(key interface{}) bool {
+	return a.t1.Contains(key) || a.t2.Contains(key)
+}
+
+func (a *arc) Peek(key interface{}) (interface{}, bool) {
+	if value, ok := a.t1.Peek(key); ok {
+		return value, true
+	}
+	return a.t2.Peek(key)
+}
+
+func (a *arc) Remove(key interface{}) bool {
+	a.b1.Remove(key)
+	a.b2.Remove(key)
+	for _, l := range []*simplelru.LRU{a.t1, a.t2} {
+		if value, ok := l.Peek(key); ok {
+			l.Remove(key)
+			a.evict(key, value)
+			return true
+		}
+	}
+	return false
+}
+
+func (a *arc) RemoveOldest() (interface{}, interface{}, bool) {
+	return a.removeVictim(false)
+}
+
+func (a *arc) GetOldest() (interface{}, interface{}, bool) {
+	if a.victimInT1(false) {
+		return a.t1.GetOldest()
+	}
+	return a.t2.GetOldest()
+}
+
+// Keys returns the keys of t1 followed by the keys of t2, each list is
+// from oldest to newest, so it is not the recency order of all keys.
+func (a *arc) Keys() []interface{} {
+	return append(a.t1.Keys(), a.t2.Keys()...)
+}
+
+func (a *arc) Len() int {
+	return a.t1.Len() + a.t2.Len()
+}
+
+func (a *arc) Purge() {
+	for _, l := range []*simplelru.LRU{a.t1, a.t2} {
+		for _, key := range l.Keys() {
+			value, _ := l.Peek(key)
+			l.Remove(key)
+			a.evict(key, value)
+		}
+	}
+	a.b1.Purge()
+	a.b2.Purge()
+	a.p = 0
+}
+
+func (a *arc) Resize(size int) int {
+	a.size = size
+	a.p = minInt(a.p, size)
+	evicted := 0
+	for a.Len() > size {
+		if _, _, ok := a.removeVictim(false); !ok {
+			break
+		}
+		evicted++
+	}
+	for _, l := range []*simplelru.LRU{a.t1, a.b1, a.t2, a.b2} {
+		l.Resize(maxInt(1, size))
+	}
+	return evicted
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,221 @@
+package lruttl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := newPolicy(CachePolicyLRU, 0, nil)
+	assert.NotNil(err)
+	_, err = newPolicy(CachePolicy(100), 10, nil)
+	assert.NotNil(err)
+	assert.Panics(func() {
+		New(10, time.Minute, CachePolicyOption(CachePolicy(100)))
+	})
+}
+
+func TestPolicyCommon(t *testing.T) {
+	for _, policy := range []CachePolicy{
+		CachePolicyLRU,
+		CachePolicy2Q,
+		CachePolicyARC,
+	} {
+		assert := assert.New(t)
+		evicted := make([]interface{}, 0)
+		l, err := newPolicy(policy, 4, func(key, value interface{}) {
+			evicted = append(evicted, key)
+		})
+		assert.Nil(err)
+
+		for i := 0; i < 4; i++ {
+			assert.False(l.Add(i, i))
+		}
+		assert.Equal(4, l.Len())
+		value, ok := l.Get(1)
+		assert.True(ok)
+		assert.Equal(1, value)
+		value, ok = l.Peek(2)
+		assert.True(ok)
+		assert.Equal(2, value)
+		assert.True(l.Contains(3))
+		assert.False(l.Contains(4))
+
+		// 满了之后淘汰的元素与GetOldest一致
+		oldest, _, ok := l.GetOldest()
+		assert.True(ok)
+		assert.True(l.Add(4, 4))
+		assert.Equal([]interface{}{oldest}, evicted)
+		assert.False(l.Contains(oldest))
+		assert.Equal(4, l.Len())
+		assert.Equal(4, len(l.Keys()))
+
+		// 删除元素也触发evicted
+		assert.True(l.Remove(4))
+		assert.False(l.Remove(4))
+		assert.Equal(4, evicted[len(evicted)-1])
+
+		oldest, _, _ = l.GetOldest()
+		key, _, ok := l.RemoveOldest()
+		assert.True(ok)
+		assert.Equal(oldest, key)
+
+		assert.Equal(1, l.Resize(1))
+		assert.Equal(1, l.Len())
+		l.Purge()
+		assert.Equal(0, l.Len())
+		assert.Equal(5, len(evicted))
+		_, _, ok = l.RemoveOldest()
+		assert.False(ok)
+		_, _, ok = l.GetOldest()
+		assert.False(ok)
+	}
+}
+
+func TestTwoQueue(t *testing.T) {
+	assert := assert.New(t)
+
+	q := newTwoQueue(4, nil)
+	// 访问两次的元素移至frequent
+	q.Add("a", 1)
+	q.Get("a")
+	assert.True(q.frequent.Contains("a"))
+	q.Add("b", 1)
+	q.Add("b", 2)
+	assert.True(q.frequent.Contains("b"))
+
+	// 只访问一次的元素不会淘汰frequent中的元素
+	for i := 0; i < 10; i++ {
+		q.Add(i, i)
+	}
+	assert.True(q.Contains("a"))
+	assert.True(q.Contains("b"))
+	assert.Equal(4, q.Len())
+
+	// 最近被淘汰的元素重新添加，直接添加至frequent
+	assert.True(q.ghost.Contains(7))
+	q.Add(7, 7)
+	assert.True(q.frequent.Contains(7))
+	assert.False(q.ghost.Contains(7))
+
+	// keys为recent之后是frequent
+	q = newTwoQueue(4, nil)
+	q.Add("a", 1)
+	q.Add("b", 1)
+	q.Get("a")
+	q.Add("c", 1)
+	assert.Equal([]interface{}{"b", "c", "a"}, q.Keys())
+}
+
+func TestARC(t *testing.T) {
+	assert := assert.New(t)
+
+	a := newARC(4, nil)
+	a.Add("a", 1)
+	a.Get("a")
+	assert.True(a.t2.Contains("a"))
+	a.Add("b", 1)
+	a.Add("b", 2)
+	assert.True(a.t2.Contains("b"))
+
+	for i := 0; i < 10; i++ {
+		a.Add(i, i)
+	}
+	assert.True(a.Contains("a"))
+	assert.True(a.Contains("b"))
+	assert.Equal(4, a.Len())
+
+	// 命中b1，t1的目标大小增大
+	assert.True(a.b1.Contains(7))
+	a.Add(7, 7)
+	assert.Equal(1, a.p)
+	assert.True(a.t2.Contains(7))
+
+	// 命中b2，t1的目标大小减小
+	_, _, ok := a.RemoveOldest()
+	assert.True(ok)
+	for i := 10; i < 20; i++ {
+		a.Add(i, i)
+		a.Get(i)
+	}
+	assert.True(a.b2.Len() != 0)
+	key := a.b2.Keys()[0]
+	a.Add(key, key)
+	assert.Equal(0, a.p)
+
+	// keys为t1之后是t2
+	a = newARC(4, nil)
+	a.Add("a", 1)
+	a.Add("b", 1)
+	a.Get("a")
+	a.Add("c", 1)
+	assert.Equal([]interface{}{"b", "c", "a"}, a.Keys())
+}
+
+func TestCachePolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, policy := range []CachePolicy{
+		CachePolicy2Q,
+		CachePolicyARC,
+	} {
+		reasons := make(map[Key]EvictReason)
+		cache := New(2, time.Minute, CachePolicyOption(policy), CacheEvictedReasonOption(func(key Key, value interface{}, reason EvictReason) {
+			reasons[key] = reason
+		}))
+		cache.Add("a", 1)
+		cache.Add("a", 2)
+		assert.Equal(EvictReasonReplaced, reasons["a"])
+		cache.Add("b", 1)
+		cache.Add("c", 1)
+		assert.Equal(EvictReasonCapacity, reasons["b"])
+		value, ok := cache.Get("a")
+		assert.True(ok)
+		assert.Equal(2, value)
+
+		cache.Remove("a")
+		assert.Equal(EvictReasonRemoved, reasons["a"])
+
+		cache.Add("d", 1, time.Nanosecond)
+		time.Sleep(time.Millisecond)
+		_, ok = cache.Get("d")
+		assert.False(ok)
+		assert.Equal(EvictReasonExpired, reasons["d"])
+		assert.Equal(1, cache.Len())
+	}
+}
+
+func TestCachePolicyMaxCost(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, policy := range []CachePolicy{
+		CachePolicyLRU,
+		CachePolicy2Q,
+		CachePolicyARC,
+	} {
+		cache := New(3, time.Minute, CachePolicyOption(policy), CacheMaxCostOption(10))
+		assert.True(cache.TryAdd("a", "12345"))
+		// 再次访问，2Q与ARC将a移至frequent列表
+		_, ok := cache.Get("a")
+		assert.True(ok)
+
+		added := cache.TryAdd("b", "123456")
+		_, ok = cache.Peek("b")
+		// 添加的数据被淘汰时返回false
+		assert.Equal(ok, added)
+		assert.True(cache.Cost() <= 10)
+		if policy == CachePolicyLRU {
+			assert.True(added)
+			_, ok = cache.Peek("a")
+			assert.False(ok)
+		} else {
+			assert.False(added)
+			_, ok = cache.Peek("a")
+			assert.True(ok)
+		}
+	}
+}
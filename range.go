@@ -0,0 +1,67 @@
+// Copyright 2022 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lruttl
+
+import "time"
+
+// CacheEntry is the entry of cache
+type CacheEntry[K comparable, V any] struct {
+	Key   K
+	Value V
+	// TTL is the remaining ttl of entry, it is -1 if the entry has no expiration
+	TTL time.Duration
+}
+
+// Entries returns the snapshot of entries which are not expired, in the order of Keys.
+// The entries are not marked as recently used.
+func (c *TypedCache[K, V]) Entries() []CacheEntry[K, V] {
+	snapshot := c.snapshotEntries()
+	now := c.clock.Now()
+	entries := make([]CacheEntry[K, V], len(snapshot))
+	for i, item := range snapshot {
+		ttl := time.Duration(-1)
+		if item.item.expiredAt != noExpiration {
+			ttl = time.Duration(item.item.expiredAt - now)
+		}
+		entries[i] = CacheEntry[K, V]{
+			Key:   item.key,
+			Value: item.item.value,
+			TTL:   ttl,
+		}
+	}
+	return entries
+}
+
+// Range calls the function for each entry which is not expired, in the order of Keys,
+// it stops if the function returns false. The entries are not marked as recently used.
+// It iterates over a snapshot of cache, so the function can call the functions of cache.
+func (c *TypedCache[K, V]) Range(fn func(key K, value V, ttl time.Duration) bool) {
+	for _, entry := range c.Entries() {
+		if !fn(entry.Key, entry.Value, entry.TTL) {
+			return
+		}
+	}
+}
+
+// RangeNewest is the same as Range, but it iterates in the reverse order of Keys
+func (c *TypedCache[K, V]) RangeNewest(fn func(key K, value V, ttl time.Duration) bool) {
+	entries := c.Entries()
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if !fn(entry.Key, entry.Value, entry.TTL) {
+			return
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package lruttl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEntries(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := NewTyped[string, int](10, time.Minute)
+	cache.Add("a", 1)
+	cache.Add("b", 2, time.Second)
+	cache.Add("c", 3, time.Nanosecond)
+	cache.Add("d", 4)
+	cache.Persist("d")
+	time.Sleep(time.Millisecond)
+
+	entries := cache.Entries()
+	assert.Equal(3, len(entries))
+	assert.Equal("a", entries[0].Key)
+	assert.Equal(1, entries[0].Value)
+	assert.True(entries[0].TTL > 59*time.Second)
+	assert.Equal("b", entries[1].Key)
+	assert.True(entries[1].TTL > 0 && entries[1].TTL <= time.Second)
+	assert.Equal("d", entries[2].Key)
+	assert.Equal(time.Duration(-1), entries[2].TTL)
+	// 过期的数据不会被删除，也不会调整顺序
+	assert.Equal([]string{"a", "b", "c", "d"}, cache.Keys())
+}
+
+func TestRange(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := NewTyped[string, int](10, time.Minute)
+	cache.Add("a", 1)
+	cache.Add("b", 2)
+	cache.Add("c", 3)
+
+	keys := make([]string, 0)
+	cache.Range(func(key string, value int, ttl time.Duration) bool {
+		keys = append(keys, key)
+		// 可以在函数中调用缓存的函数
+		cache.Get(key)
+		return key != "b"
+	})
+	assert.Equal([]string{"a", "b"}, keys)
+	assert.Equal([]string{"c", "a", "b"}, cache.Keys())
+
+	keys = keys[:0]
+	cache.RangeNewest(func(key string, value int, ttl time.Duration) bool {
+		keys = append(keys, key)
+		return true
+	})
+	assert.Equal([]string{"b", "a", "c"}, keys)
+
+	keys = keys[:0]
+	cache.RangeNewest(func(key string, value int, ttl time.Duration) bool {
+		keys = append(keys, key)
+		return false
+	})
+	assert.Equal([]string{"b"}, keys)
+}
@@ -0,0 +1,167 @@
+// Copyright 2022 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lruttl
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrLoaderPanic is the error returned to the waiters when the loader panics
+var ErrLoaderPanic = errors.New("loader panic")
+
+type flightCall[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// flightGroup merges the concurrent calls of the same key into one
+type flightGroup[K comparable, V any] struct {
+	mu    sync.Mutex
+	calls map[K]*flightCall[V]
+}
+
+// goDo executes the function in a new goroutine if there is no call of the key in flight,
+// otherwise it returns directly without waiting.
+func (g *flightGroup[K, V]) goDo(key K, fn func() (V, error)) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[K]*flightCall[V])
+	}
+	if _, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		return
+	}
+	call := &flightCall[V]{
+		done: make(chan struct{}),
+	}
+	g.calls[key] = call
+	g.mu.Unlock()
+	go func() {
+		// 后台执行的出错无需返回，如果panic则忽略
+		defer func() {
+			_ = recover()
+		}()
+		_, _ = g.run(key, call, fn)
+	}()
+}
+
+// do executes the function and returns its result, the callers of
+// the same key will wait for the first one and share its result.
+func (g *flightGroup[K, V]) do(key K, fn func() (V, error)) (V, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[K]*flightCall[V])
+	}
+	// 已有相同key的调用，等待其完成
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		<-call.done
+		return call.value, call.err
+	}
+	call := &flightCall[V]{
+		done: make(chan struct{}),
+	}
+	g.calls[key] = call
+	g.mu.Unlock()
+	return g.run(key, call, fn)
+}
+
+// doContext executes the function in a new goroutine if there is no call of the key in flight,
+// all the callers (the first one included) wait for the shared result or return the error of
+// their own context. The function should not use the context of any caller, see detachContext.
+func (g *flightGroup[K, V]) doContext(ctx context.Context, key K, fn func() (V, error)) (V, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[K]*flightCall[V])
+	}
+	call, ok := g.calls[key]
+	if !ok {
+		call = &flightCall[V]{
+			done: make(chan struct{}),
+		}
+		g.calls[key] = call
+		go func() {
+			// panic时等待者返回ErrLoaderPanic
+			defer func() {
+				_ = recover()
+			}()
+			_, _ = g.run(key, call, fn)
+		}()
+	}
+	g.mu.Unlock()
+	select {
+	case <-call.done:
+		return call.value, call.err
+	case <-ctx.Done():
+		var value V
+		return value, ctx.Err()
+	}
+}
+
+// detachedContext keeps the values of parent, but it is never canceled
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
+
+// detachContext returns a context which can not be canceled by the parent,
+// it is used by the shared call, so one caller canceled does not fail the others.
+// The returned context is done after the timeout, it has no deadline if the timeout is 0.
+func detachContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	detached := detachedContext{
+		parent: ctx,
+	}
+	if timeout <= 0 {
+		return detached, func() {}
+	}
+	return context.WithTimeout(detached, timeout)
+}
+
+// run executes the function of the call, and wakes up the waiters when done
+func (g *flightGroup[K, V]) run(key K, call *flightCall[V], fn func() (V, error)) (V, error) {
+	normalReturn := false
+	defer func() {
+		// 如果函数panic，则等待者返回出错
+		if !normalReturn {
+			call.err = ErrLoaderPanic
+		}
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(call.done)
+	}()
+	call.value, call.err = fn()
+	normalReturn = true
+	return call.value, call.err
+}
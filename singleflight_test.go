@@ -0,0 +1,175 @@
+package lruttl
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlightGroup(t *testing.T) {
+	assert := assert.New(t)
+
+	g := flightGroup[string, int]{}
+	var count int32
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := g.do("a", func() (int, error) {
+				atomic.AddInt32(&count, 1)
+				time.Sleep(50 * time.Millisecond)
+				return 1, nil
+			})
+			assert.Nil(err)
+			assert.Equal(1, value)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(int32(1), atomic.LoadInt32(&count))
+	assert.Empty(g.calls)
+
+	// 出错时返回错误
+	customErr := errors.New("custom error")
+	_, err := g.do("a", func() (int, error) {
+		return 0, customErr
+	})
+	assert.Equal(customErr, err)
+}
+
+func TestFlightGroupPanic(t *testing.T) {
+	assert := assert.New(t)
+
+	g := flightGroup[string, int]{}
+	done := make(chan error)
+	go func() {
+		// 等待第一个调用开始
+		time.Sleep(10 * time.Millisecond)
+		_, err := g.do("a", func() (int, error) {
+			return 1, nil
+		})
+		done <- err
+	}()
+	assert.Panics(func() {
+		_, _ = g.do("a", func() (int, error) {
+			time.Sleep(50 * time.Millisecond)
+			panic("fail")
+		})
+	})
+	assert.Equal(ErrLoaderPanic, <-done)
+}
+
+func TestFlightGroupGoDo(t *testing.T) {
+	assert := assert.New(t)
+
+	g := flightGroup[string, int]{}
+	var count int32
+	for i := 0; i < 10; i++ {
+		g.goDo("a", func() (int, error) {
+			atomic.AddInt32(&count, 1)
+			time.Sleep(50 * time.Millisecond)
+			return 1, nil
+		})
+	}
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(int32(1), atomic.LoadInt32(&count))
+	g.mu.Lock()
+	assert.Empty(g.calls)
+	g.mu.Unlock()
+}
+
+func TestFlightGroupDoContext(t *testing.T) {
+	assert := assert.New(t)
+
+	g := flightGroup[string, int]{}
+	started := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		value, err := g.doContext(context.Background(), "a", func() (int, error) {
+			close(started)
+			time.Sleep(100 * time.Millisecond)
+			return 1, nil
+		})
+		assert.Nil(err)
+		assert.Equal(1, value)
+	}()
+	<-started
+
+	// 等待者的context超时则直接返回
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := g.doContext(ctx, "a", func() (int, error) {
+		return 2, nil
+	})
+	assert.Equal(context.DeadlineExceeded, err)
+
+	// 未超时的等待者共享结果
+	value, err := g.doContext(context.Background(), "a", func() (int, error) {
+		return 2, nil
+	})
+	assert.Nil(err)
+	assert.Equal(1, value)
+	<-done
+}
+
+func TestFlightGroupDoContextFirstCallerCanceled(t *testing.T) {
+	assert := assert.New(t)
+
+	g := flightGroup[string, int]{}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		_, err := g.doContext(ctx, "a", func() (int, error) {
+			time.Sleep(50 * time.Millisecond)
+			return 1, nil
+		})
+		done <- err
+	}()
+	time.Sleep(5 * time.Millisecond)
+	// 第一个调用者超时后，其它调用者仍等待共享的结果
+	value, err := g.doContext(context.Background(), "a", func() (int, error) {
+		return 2, nil
+	})
+	assert.Nil(err)
+	assert.Equal(1, value)
+	assert.Equal(context.DeadlineExceeded, <-done)
+
+	// panic时返回出错
+	_, err = g.doContext(context.Background(), "a", func() (int, error) {
+		panic("fail")
+	})
+	assert.Equal(ErrLoaderPanic, err)
+}
+
+func TestDetachContext(t *testing.T) {
+	assert := assert.New(t)
+
+	type contextKey struct{}
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), contextKey{}, "a"))
+	cancel()
+	detached, detachedCancel := detachContext(ctx, 0)
+	defer detachedCancel()
+	assert.Nil(detached.Err())
+	assert.Nil(detached.Done())
+	_, ok := detached.Deadline()
+	assert.False(ok)
+	assert.Equal("a", detached.Value(contextKey{}))
+
+	// 设置超时则有deadline
+	detached, detachedCancel = detachContext(ctx, time.Second)
+	defer detachedCancel()
+	assert.Nil(detached.Err())
+	deadline, ok := detached.Deadline()
+	assert.True(ok)
+	assert.True(time.Until(deadline) <= time.Second)
+	assert.Equal("a", detached.Value(contextKey{}))
+	detachedCancel()
+	assert.Equal(context.Canceled, detached.Err())
+}
@@ -0,0 +1,257 @@
+// Copyright 2022 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The snapshot of cache is a binary format:
+// magic(6 bytes) | version(1 byte) | count(uvarint) | entries
+// and each entry is:
+// key length(uvarint) | key | value length(uvarint) | value | expired at(varint) | deadline(varint)
+// The deadline is the max expired time of item in sliding mode, 0 means no limit.
+// The cost is not saved, it is recomputed by the cost function of cache when loaded.
+// The entries are written in the order of Keys, from oldest to newest for the lru policy.
+
+package lruttl
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	snapshotMagic   = "LRUTTL"
+	snapshotVersion = 1
+)
+
+// ErrInvalidSnapshot is the error of invalid snapshot data
+var ErrInvalidSnapshot = errors.New("invalid snapshot")
+
+// CacheMarshal is the marshal function of cache snapshot
+type CacheMarshal func(v interface{}) ([]byte, error)
+
+// CacheUnmarshal is the unmarshal function of cache snapshot
+type CacheUnmarshal func(data []byte, v interface{}) error
+
+// CacheCodecOption sets the marshal and unmarshal function for the keys and values of snapshot,
+// it will be json.Marshal and json.Unmarshal if not set. The json can not keep the type of
+// interface{}, so a custom codec should be set if the cache is not typed.
+func CacheCodecOption(marshal CacheMarshal, unmarshal CacheUnmarshal) CacheOption {
+	return func(opts *cacheOptions) {
+		opts.marshal = marshal
+		opts.unmarshal = unmarshal
+	}
+}
+
+type snapshotEntry[K comparable, V any] struct {
+	key  K
+	item *cacheItem[V]
+}
+
+// Save writes the snapshot of cache to writer, the expired items are skipped
+func (c *TypedCache[K, V]) Save(w io.Writer) error {
+	entries := c.snapshotEntries()
+	// 快照中保存的是系统时间，便于在其它进程中加载
+	clockNow := c.clock.Now()
+	wallNow := time.Now().UnixNano()
+	marshal := c.marshal
+	if marshal == nil {
+		marshal = json.Marshal
+	}
+
+	bw := bufio.NewWriter(w)
+	_, _ = bw.WriteString(snapshotMagic)
+	_ = bw.WriteByte(snapshotVersion)
+	buf := make([]byte, binary.MaxVarintLen64)
+	writeUvarint := func(v uint64) {
+		n := binary.PutUvarint(buf, v)
+		_, _ = bw.Write(buf[:n])
+	}
+	writeVarint := func(v int64) {
+		n := binary.PutVarint(buf, v)
+		_, _ = bw.Write(buf[:n])
+	}
+	writeUvarint(uint64(len(entries)))
+	for _, entry := range entries {
+		key, err := marshal(entry.key)
+		if err != nil {
+			return err
+		}
+		value, err := marshal(entry.item.value)
+		if err != nil {
+			return err
+		}
+		writeUvarint(uint64(len(key)))
+		_, _ = bw.Write(key)
+		writeUvarint(uint64(len(value)))
+		_, _ = bw.Write(value)
+		expiredAt := entry.item.expiredAt
+		if expiredAt != noExpiration {
+			expiredAt = expiredAt - clockNow + wallNow
+		}
+		writeVarint(expiredAt)
+		deadline := entry.item.deadline
+		if deadline > 0 {
+			deadline = deadline - clockNow + wallNow
+		}
+		writeVarint(deadline)
+	}
+	// bufio的写入出错会在flush时返回
+	return bw.Flush()
+}
+
+// snapshotEntries returns the entries which are not expired, in the order of Keys
+func (c *TypedCache[K, V]) snapshotEntries() []snapshotEntry[K, V] {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	keys := c.lru.Keys()
+	entries := make([]snapshotEntry[K, V], 0, len(keys))
+	now := c.clock.Now()
+	for _, key := range keys {
+		data, _ := c.lru.Peek(key)
+		item, _ := data.(*cacheItem[V])
+		if item == nil || item.isExpired(now) {
+			continue
+		}
+		k, _ := key.(K)
+		// 复制元素，避免在锁外读取时被修改
+		copied := *item
+		entries = append(entries, snapshotEntry[K, V]{
+			key:  k,
+			item: &copied,
+		})
+	}
+	return entries
+}
+
+// Load reads the snapshot from reader and adds the items to cache,
+// the expired items are skipped and the order of Keys is preserved.
+// For the 2Q and ARC policy, all the items are loaded as recent.
+func (c *TypedCache[K, V]) Load(r io.Reader) error {
+	c.mu.RLock()
+	closed := c.closed
+	c.mu.RUnlock()
+	if closed {
+		return ErrCacheClosed
+	}
+	unmarshal := c.unmarshal
+	if unmarshal == nil {
+		unmarshal = json.Unmarshal
+	}
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		reader := bufio.NewReader(r)
+		r = reader
+		br = reader
+	}
+	header := make([]byte, len(snapshotMagic)+1)
+	_, err := io.ReadFull(r, header)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(header[:len(snapshotMagic)], []byte(snapshotMagic)) {
+		return ErrInvalidSnapshot
+	}
+	version := header[len(snapshotMagic)]
+	if version != snapshotVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidSnapshot, version)
+	}
+	readBytes := func() ([]byte, error) {
+		size, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, err
+		}
+		// 按实际读取的数据分配内存，避免错误的长度导致分配过大的内存
+		buf := bytes.Buffer{}
+		_, err = io.CopyN(&buf, r, int64(size))
+		if err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	count, err := binary.ReadUvarint(br)
+	if err != nil {
+		return err
+	}
+	entries := make([]snapshotEntry[K, V], 0)
+	clockNow := c.clock.Now()
+	wallNow := time.Now().UnixNano()
+	for i := uint64(0); i < count; i++ {
+		keyData, err := readBytes()
+		if err != nil {
+			return err
+		}
+		valueData, err := readBytes()
+		if err != nil {
+			return err
+		}
+		expiredAt, err := binary.ReadVarint(br)
+		if err != nil {
+			return err
+		}
+		deadline, err := binary.ReadVarint(br)
+		if err != nil {
+			return err
+		}
+		// 已过期的数据忽略
+		if expiredAt < wallNow {
+			continue
+		}
+		var key K
+		err = unmarshal(keyData, &key)
+		if err != nil {
+			return err
+		}
+		// 快照中未保存原始的ttl，使用剩余的时间
+		item := &cacheItem[V]{
+			expiredAt: noExpiration,
+		}
+		// 将系统时间转换为缓存的时钟
+		if expiredAt != noExpiration {
+			item.ttl = expiredAt - wallNow
+			item.expiredAt = clockNow + item.ttl
+			if c.sliding && c.maxLifetime > 0 {
+				item.deadline = clockNow + c.maxLifetime.Nanoseconds()
+				// 保留快照中的最大生存时间，避免每次加载后重新计算
+				if deadline > 0 && deadline-wallNow+clockNow < item.deadline {
+					item.deadline = deadline - wallNow + clockNow
+				}
+				if item.expiredAt > item.deadline {
+					item.expiredAt = item.deadline
+				}
+			}
+		}
+		err = unmarshal(valueData, &item.value)
+		if err != nil {
+			return err
+		}
+		item.cost = c.itemCost(key, item.value)
+		entries = append(entries, snapshotEntry[K, V]{
+			key:  key,
+			item: item,
+		})
+	}
+
+	c.mu.Lock()
+	defer c.unlock()
+	// 从旧至新添加，保证最近使用的顺序
+	for _, entry := range entries {
+		c.addLocked(entry.key, entry.item)
+	}
+	return nil
+}
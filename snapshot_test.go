@@ -0,0 +1,193 @@
+package lruttl
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := NewTyped[string, []string](10, time.Minute)
+	cache.Add("a", []string{"1"})
+	cache.Add("b", []string{"2"}, 30*time.Second)
+	cache.Add("c", []string{"3"})
+	cache.Add("expired", []string{"4"}, time.Nanosecond)
+	cache.Get("a")
+	time.Sleep(time.Millisecond)
+
+	buf := &bytes.Buffer{}
+	err := cache.Save(buf)
+	assert.Nil(err)
+
+	newCache := NewTyped[string, []string](10, time.Minute)
+	err = newCache.Load(bytes.NewReader(buf.Bytes()))
+	assert.Nil(err)
+	// 过期的数据不保存，并保持最近使用的顺序
+	assert.Equal([]string{"b", "c", "a"}, newCache.Keys())
+	value, ok := newCache.Peek("a")
+	assert.True(ok)
+	assert.Equal([]string{"1"}, value)
+	ttl := newCache.TTL("b")
+	assert.True(ttl > 29*time.Second && ttl <= 30*time.Second)
+}
+
+func TestSnapshotSkipExpired(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := New(10, time.Minute)
+	cache.Add("a", "1", 10*time.Millisecond)
+	cache.Add("b", "2")
+	buf := &bytes.Buffer{}
+	err := cache.Save(buf)
+	assert.Nil(err)
+
+	time.Sleep(20 * time.Millisecond)
+	newCache := New(10, time.Minute)
+	// 非ByteReader的reader
+	err = newCache.Load(struct {
+		*bytes.Buffer
+	}{buf})
+	assert.Nil(err)
+	assert.Equal([]Key{"b"}, newCache.Keys())
+}
+
+type gobCodecValue struct {
+	Value interface{}
+}
+
+func gobMarshal(v interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	err := gob.NewEncoder(buf).Encode(&gobCodecValue{Value: v})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func gobUnmarshal(data []byte, v interface{}) error {
+	result := gobCodecValue{}
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&result)
+	if err != nil {
+		return err
+	}
+	switch p := v.(type) {
+	case *Key:
+		*p = result.Value
+	case *interface{}:
+		*p = result.Value
+	}
+	return nil
+}
+
+func TestSnapshotCodec(t *testing.T) {
+	assert := assert.New(t)
+
+	opts := []CacheOption{
+		CacheCodecOption(gobMarshal, gobUnmarshal),
+	}
+	cache := New(10, time.Minute, opts...)
+	cache.Add(1, int64(2))
+	buf := &bytes.Buffer{}
+	err := cache.Save(buf)
+	assert.Nil(err)
+
+	newCache := New(10, time.Minute, opts...)
+	err = newCache.Load(buf)
+	assert.Nil(err)
+	// 使用gob可保留类型
+	value, ok := newCache.Get(1)
+	assert.True(ok)
+	assert.Equal(int64(2), value)
+}
+
+func TestSnapshotInvalid(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := New(10, time.Minute)
+	err := cache.Load(bytes.NewBufferString("abcdefg"))
+	assert.Equal(ErrInvalidSnapshot, err)
+
+	err = cache.Load(bytes.NewBufferString(snapshotMagic + "\x02"))
+	assert.ErrorIs(err, ErrInvalidSnapshot)
+	assert.Equal("invalid snapshot: unsupported version 2", err.Error())
+
+	err = cache.Load(bytes.NewBufferString("abc"))
+	assert.NotNil(err)
+
+	// 数据不完整
+	cache.Add("a", "1")
+	buf := &bytes.Buffer{}
+	err = cache.Save(buf)
+	assert.Nil(err)
+	err = New(10, time.Minute).Load(bytes.NewReader(buf.Bytes()[:buf.Len()-3]))
+	assert.NotNil(err)
+}
+
+func TestSnapshotPersist(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := New(10, time.Minute)
+	cache.Add("a", "1")
+	cache.Persist("a")
+	buf := &bytes.Buffer{}
+	err := cache.Save(buf)
+	assert.Nil(err)
+
+	newCache := New(10, time.Minute, CacheSlidingOption(0))
+	err = newCache.Load(buf)
+	assert.Nil(err)
+	_, ok := newCache.Get("a")
+	assert.True(ok)
+	assert.Equal(time.Duration(-1), newCache.TTL("a"))
+}
+
+func TestSnapshotRecomputeCost(t *testing.T) {
+	assert := assert.New(t)
+
+	// 无cost限制的缓存保存的快照，加载至有cost限制的缓存
+	cache := New(10, time.Minute)
+	cache.Add("a", "0123456789")
+	cache.Add("b", "0123456789")
+	buf := &bytes.Buffer{}
+	assert.Nil(cache.Save(buf))
+
+	costCache := NewTyped[string, string](10, time.Minute, CacheMaxCostOption(15))
+	assert.Nil(costCache.Load(bytes.NewReader(buf.Bytes())))
+	assert.Equal(1, costCache.Len())
+	assert.Equal(int64(10), costCache.Cost())
+	_, ok := costCache.Peek("b")
+	assert.True(ok)
+}
+
+func TestSnapshotMaxLifetime(t *testing.T) {
+	assert := assert.New(t)
+
+	clock := NewFakeClock(time.Now())
+	cache := New(10, 5*time.Second, CacheSlidingOption(10*time.Second), CacheClockOption(clock))
+	cache.Add("a", 1)
+	for i := 0; i < 2; i++ {
+		clock.Advance(4 * time.Second)
+		_, ok := cache.Get("a")
+		assert.True(ok)
+	}
+	buf := &bytes.Buffer{}
+	assert.Nil(cache.Save(buf))
+
+	// 加载后保留原有的最大生存时间
+	newClock := NewFakeClock(time.Now())
+	newCache := New(10, 5*time.Second, CacheSlidingOption(10*time.Second), CacheClockOption(newClock))
+	assert.Nil(newCache.Load(buf))
+	newClock.Advance(time.Second)
+	_, ok := newCache.Get("a")
+	assert.True(ok)
+	ttl := newCache.TTL("a")
+	assert.True(ttl > 0 && ttl <= time.Second)
+	newClock.Advance(2 * time.Second)
+	_, ok = newCache.Get("a")
+	assert.False(ok)
+}
@@ -0,0 +1,113 @@
+// Copyright 2022 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lruttl
+
+import "sync/atomic"
+
+// CacheStats is the statistics snapshot of cache
+type CacheStats struct {
+	// Hits is the count of Get which returns a valid value
+	Hits uint64
+	// Misses is the count of Get which does not find the key
+	Misses uint64
+	// ExpiredHits is the count of Get which finds an expired value
+	ExpiredHits uint64
+	// Adds is the count of new keys added to cache
+	Adds uint64
+	// Updates is the count of existing keys updated
+	Updates uint64
+	// Rejects is the count of new keys rejected by admission policy
+	Rejects uint64
+	// Removals is the count of keys removed by Remove function
+	Removals uint64
+	// Evictions is the count of evicted keys by reason,
+	// includes capacity, expired and purged
+	Evictions map[EvictReason]uint64
+}
+
+// HitRatio returns the ratio of hits in all Get,
+// the expired hits are treated as misses
+func (s CacheStats) HitRatio() float64 {
+	total := s.Hits + s.Misses + s.ExpiredHits
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// cacheCounter is the atomic counters of cache
+type cacheCounter struct {
+	hits        atomic.Uint64
+	misses      atomic.Uint64
+	expiredHits atomic.Uint64
+	adds        atomic.Uint64
+	updates     atomic.Uint64
+	rejects     atomic.Uint64
+	// evictions is the count of evicted keys, index by reason
+	evictions [EvictReasonPurged + 1]atomic.Uint64
+}
+
+// evict increases the count of eviction by reason
+func (cc *cacheCounter) evict(reason EvictReason) {
+	if reason < 0 || int(reason) >= len(cc.evictions) {
+		return
+	}
+	cc.evictions[reason].Add(1)
+}
+
+// snapshot returns the statistics of counters
+func (cc *cacheCounter) snapshot() CacheStats {
+	stats := CacheStats{
+		Hits:        cc.hits.Load(),
+		Misses:      cc.misses.Load(),
+		ExpiredHits: cc.expiredHits.Load(),
+		Adds:        cc.adds.Load(),
+		Updates:     cc.updates.Load(),
+		Rejects:     cc.rejects.Load(),
+		Removals:    cc.evictions[EvictReasonRemoved].Load(),
+		Evictions:   make(map[EvictReason]uint64),
+	}
+	for _, reason := range []EvictReason{
+		EvictReasonCapacity,
+		EvictReasonExpired,
+		EvictReasonPurged,
+	} {
+		stats.Evictions[reason] = cc.evictions[reason].Load()
+	}
+	return stats
+}
+
+// reset resets all counters to zero
+func (cc *cacheCounter) reset() {
+	cc.hits.Store(0)
+	cc.misses.Store(0)
+	cc.expiredHits.Store(0)
+	cc.adds.Store(0)
+	cc.updates.Store(0)
+	cc.rejects.Store(0)
+	for i := range cc.evictions {
+		cc.evictions[i].Store(0)
+	}
+}
+
+// Stats returns the statistics snapshot of cache
+func (c *TypedCache[K, V]) Stats() CacheStats {
+	return c.counter.snapshot()
+}
+
+// ResetStats resets the statistics of cache
+func (c *TypedCache[K, V]) ResetStats() {
+	c.counter.reset()
+}
@@ -0,0 +1,59 @@
+package lruttl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheStats(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := New(2, time.Minute)
+	assert.Equal(float64(0), cache.Stats().HitRatio())
+
+	cache.Add("a", 1)
+	cache.Add("a", 2)
+	cache.Add("b", 1)
+	cache.Add("c", 1, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	cache.Get("a")
+	cache.Get("b")
+	cache.Get("c")
+	cache.Get("d")
+	cache.Remove("b")
+
+	stats := cache.Stats()
+	assert.Equal(CacheStats{
+		Hits:        1,
+		Misses:      2,
+		ExpiredHits: 1,
+		Adds:        3,
+		Updates:     1,
+		Removals:    1,
+		Evictions: map[EvictReason]uint64{
+			EvictReasonCapacity: 1,
+			EvictReasonExpired:  1,
+			EvictReasonPurged:   0,
+		},
+	}, stats)
+	assert.Equal(0.25, stats.HitRatio())
+
+	cache.ResetStats()
+	stats = cache.Stats()
+	assert.Equal(uint64(0), stats.Hits)
+	assert.Equal(uint64(0), stats.Adds)
+	assert.Equal(uint64(0), stats.Evictions[EvictReasonCapacity])
+}
+
+func BenchmarkCacheGet(b *testing.B) {
+	cache := New(10, time.Minute)
+	cache.Add("a", 1)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cache.Get("a")
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright 2022 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// TinyLFU is an admission policy for cache, it estimates the access frequency
+// of keys by a count-min sketch and a doorkeeper (bloom filter).
+// The counters are halved periodically, so the old frequency will be decayed.
+
+package lruttl
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+const (
+	// cmDepth is the count of count-min sketch rows
+	cmDepth = 4
+	// cmMaxCount is the max value of counter
+	cmMaxCount = 15
+)
+
+// cmSketch is a count-min sketch with 4 rows
+type cmSketch struct {
+	rows [cmDepth][]uint8
+	mask uint64
+}
+
+func newCMSketch(numCounters int) *cmSketch {
+	size := nextPowerOfTwo(numCounters)
+	s := &cmSketch{
+		mask: uint64(size - 1),
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, size)
+	}
+	return s
+}
+
+// index returns the counter index of row
+func (s *cmSketch) index(h uint64, row int) uint64 {
+	// 双重哈希生成每行的索引
+	h2 := bits.RotateLeft64(h, 32)
+	return (h + uint64(row)*h2) & s.mask
+}
+
+func (s *cmSketch) increment(h uint64) {
+	for i := range s.rows {
+		index := s.index(h, i)
+		if s.rows[i][index] < cmMaxCount {
+			s.rows[i][index]++
+		}
+	}
+}
+
+func (s *cmSketch) estimate(h uint64) int {
+	min := uint8(cmMaxCount)
+	for i := range s.rows {
+		value := s.rows[i][s.index(h, i)]
+		if value < min {
+			min = value
+		}
+	}
+	return int(min)
+}
+
+// reset halves all the counters
+func (s *cmSketch) reset() {
+	for _, row := range s.rows {
+		for i := range row {
+			row[i] >>= 1
+		}
+	}
+}
+
+// doorkeeper is a bloom filter, it filters the keys which are accessed only once
+type doorkeeper struct {
+	bits []uint64
+	mask uint64
+}
+
+func newDoorkeeper(numBits int) *doorkeeper {
+	size := nextPowerOfTwo(numBits)
+	if size < 64 {
+		size = 64
+	}
+	return &doorkeeper{
+		bits: make([]uint64, size/64),
+		mask: uint64(size - 1),
+	}
+}
+
+// add adds the hash to doorkeeper, it returns true if the hash already exists
+func (d *doorkeeper) add(h uint64) bool {
+	exists := true
+	for _, index := range d.indexes(h) {
+		word := index / 64
+		bit := uint64(1) << (index % 64)
+		if d.bits[word]&bit == 0 {
+			exists = false
+			d.bits[word] |= bit
+		}
+	}
+	return exists
+}
+
+func (d *doorkeeper) contains(h uint64) bool {
+	for _, index := range d.indexes(h) {
+		if d.bits[index/64]&(uint64(1)<<(index%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *doorkeeper) indexes(h uint64) [2]uint64 {
+	return [2]uint64{
+		h & d.mask,
+		bits.RotateLeft64(h, 32) & d.mask,
+	}
+}
+
+func (d *doorkeeper) reset() {
+	for i := range d.bits {
+		d.bits[i] = 0
+	}
+}
+
+// tinyLFU estimates the access frequency of keys,
+// it is not thread safe and should be used with lock
+type tinyLFU struct {
+	sketch *cmSketch
+	door   *doorkeeper
+	// additions is the count of increments since last reset
+	additions int
+	// sampleSize is the count of increments to trigger aging
+	sampleSize int
+}
+
+func newTinyLFU(sampleSize int) *tinyLFU {
+	return &tinyLFU{
+		sketch:     newCMSketch(sampleSize),
+		door:       newDoorkeeper(sampleSize),
+		sampleSize: sampleSize,
+	}
+}
+
+// increment records an access of the hash
+func (t *tinyLFU) increment(h uint64) {
+	// 第一次访问只记录在doorkeeper中
+	if t.door.add(h) {
+		t.sketch.increment(h)
+	}
+	t.additions++
+	// 达到采样数量后所有计数减半
+	if t.additions >= t.sampleSize {
+		t.sketch.reset()
+		t.door.reset()
+		t.additions = 0
+	}
+}
+
+// estimate returns the estimated frequency of the hash
+func (t *tinyLFU) estimate(h uint64) int {
+	count := t.sketch.estimate(h)
+	if t.door.contains(h) {
+		count++
+	}
+	return count
+}
+
+// admit returns true if the candidate is not less frequent than the victim
+func (t *tinyLFU) admit(candidate, victim uint64) bool {
+	return t.estimate(candidate) >= t.estimate(victim)
+}
+
+func nextPowerOfTwo(n int) int {
+	if n <= 1 {
+		return 1
+	}
+	return 1 << bits.Len(uint(n-1))
+}
+
+// keyHash returns the hash of key
+func keyHash(key interface{}) uint64 {
+	var h uint64
+	switch k := key.(type) {
+	case string:
+		return MemHashString(k)
+	case []byte:
+		return MemHash(k)
+	case int:
+		h = uint64(k)
+	case int8:
+		h = uint64(k)
+	case int16:
+		h = uint64(k)
+	case int32:
+		h = uint64(k)
+	case int64:
+		h = uint64(k)
+	case uint:
+		h = uint64(k)
+	case uint8:
+		h = uint64(k)
+	case uint16:
+		h = uint64(k)
+	case uint32:
+		h = uint64(k)
+	case uint64:
+		h = k
+	default:
+		return MemHashString(fmt.Sprintf("%#v", key))
+	}
+	// 整数使用splitmix64打散
+	h += 0x9e3779b97f4a7c15
+	h = (h ^ (h >> 30)) * 0xbf58476d1ce4e5b9
+	h = (h ^ (h >> 27)) * 0x94d049bb133111eb
+	return h ^ (h >> 31)
+}
@@ -0,0 +1,119 @@
+package lruttl
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCMSketch(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newCMSketch(100)
+	assert.Equal(uint64(127), s.mask)
+	h := keyHash("a")
+	for i := 0; i < 20; i++ {
+		s.increment(h)
+	}
+	// 计数最大为15
+	assert.Equal(15, s.estimate(h))
+	assert.Equal(0, s.estimate(keyHash("b")))
+	s.reset()
+	assert.Equal(7, s.estimate(h))
+}
+
+func TestDoorkeeper(t *testing.T) {
+	assert := assert.New(t)
+
+	d := newDoorkeeper(10)
+	assert.Equal(1, len(d.bits))
+	h := keyHash("a")
+	assert.False(d.contains(h))
+	assert.False(d.add(h))
+	assert.True(d.add(h))
+	assert.True(d.contains(h))
+	d.reset()
+	assert.False(d.contains(h))
+}
+
+func TestTinyLFU(t *testing.T) {
+	assert := assert.New(t)
+
+	lfu := newTinyLFU(100)
+	a := keyHash("a")
+	b := keyHash("b")
+	for i := 0; i < 5; i++ {
+		lfu.increment(a)
+	}
+	lfu.increment(b)
+	// 第一次访问只记录在doorkeeper
+	assert.Equal(1, lfu.estimate(b))
+	assert.Equal(5, lfu.estimate(a))
+	assert.True(lfu.admit(a, b))
+	assert.False(lfu.admit(b, a))
+
+	// 达到采样数量后计数减半
+	for i := 0; i < 94; i++ {
+		lfu.increment(keyHash(i))
+	}
+	assert.Equal(0, lfu.additions)
+	assert.Equal(2, lfu.estimate(a))
+}
+
+func TestKeyHash(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(keyHash("a"), keyHash("a"))
+	assert.Equal(keyHash([]byte("a")), keyHash("a"))
+	assert.NotEqual(keyHash(1), keyHash(2))
+	assert.Equal(keyHash(int64(1)), keyHash(uint64(1)))
+	type customKey struct {
+		ID int
+	}
+	assert.Equal(keyHash(customKey{ID: 1}), keyHash(customKey{ID: 1}))
+	assert.NotEqual(keyHash(customKey{ID: 1}), keyHash(customKey{ID: 2}))
+	assert.Equal(4, nextPowerOfTwo(3))
+	assert.Equal(1, nextPowerOfTwo(0))
+}
+
+func TestCacheTinyLFU(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := New(10, time.Minute, CacheTinyLFUOption(1000))
+	for i := 0; i < 10; i++ {
+		key := "hot" + strconv.Itoa(i)
+		cache.Add(key, i)
+		for j := 0; j < 3; j++ {
+			cache.Get(key)
+		}
+	}
+	// 只访问一次的key无法替换高频的key
+	for i := 0; i < 100; i++ {
+		key := "scan" + strconv.Itoa(i)
+		cache.Get(key)
+		assert.False(cache.TryAdd(key, i))
+	}
+	for i := 0; i < 10; i++ {
+		_, ok := cache.Get("hot" + strconv.Itoa(i))
+		assert.True(ok)
+	}
+	assert.Equal(uint64(100), cache.Stats().Rejects)
+
+	// 已存在的key更新不受限制
+	assert.True(cache.TryAdd("hot0", 100))
+
+	// 访问频率高的key可以替换
+	for i := 0; i < 10; i++ {
+		cache.Get("new")
+	}
+	assert.True(cache.TryAdd("new", 1))
+}
+
+func TestCacheTinyLFUDefaultSampleSize(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := New(10, time.Minute, CacheTinyLFUOption(0))
+	assert.Equal(100, cache.admission.sampleSize)
+}